@@ -0,0 +1,175 @@
+// accents.go
+
+//
+// Unicode normalization and case folding, shared by sortInterface.go and
+// wordcount.go. This file has no main, so give it to go run or go test along
+// with the program that uses it, e.g.
+//
+//    $ go run sortInterface.go accents.go
+//    $ go run wordcount.go accents.go file.txt
+//
+
+package main
+
+import (
+    "unicode"
+)
+
+//
+// Unicode has two ways of writing an accented letter like é: as a single
+// "precomposed" character (U+00E9), or as e followed by a "combining" accent
+// (U+0301). They look identical, but are different strings. Normalization
+// Form C (NFC) converts both to the precomposed form, and Normalization Form
+// D (NFD) converts both to the combining form.
+//
+// The golang.org/x/text/unicode/norm package implements NFC and NFD fully,
+// but the standard library doesn't. Without it, this table has every
+// precomposed letter in the Latin, Greek and Cyrillic blocks (but not
+// Greek Extended) that is one letter plus one accent. Letters with two
+// accents, like ǖ or ệ, are made from a letter that already has one, so they
+// work too, as long as the accents come in the order Unicode puts them.
+//
+// Each row is a combining accent, the letters it combines with, and the
+// precomposed results, in the same order. An accent can have several rows.
+//
+var compositions = []struct {
+    accent      rune
+    letters     string
+    precomposed string
+}{
+    {'\u0300', "AEIOUaeiouÜüNnĒē", "ÀÈÌÒÙàèìòùǛǜǸǹḔḕ"}, // grave
+    {'\u0300', "ŌōWwÂâĂăÊêÔôƠơƯư", "ṐṑẀẁẦầẰằỀềỒồỜờỪừ"},
+    {'\u0300', "YyЕИеи", "ỲỳЀЍѐѝ"},
+    {'\u0301', "AEIOUYaeiouyCcLl", "ÁÉÍÓÚÝáéíóúýĆćĹĺ"}, // acute
+    {'\u0301', "NnRrSsZzÜüGgÅåÆæ", "ŃńŔŕŚśŹźǗǘǴǵǺǻǼǽ"},
+    {'\u0301', "ØøÇçĒēÏïKkMmÕõŌō", "ǾǿḈḉḖḗḮḯḰḱḾḿṌṍṒṓ"},
+    {'\u0301', "PpŨũWwÂâĂăÊêÔôƠơ", "ṔṕṸṹẂẃẤấẮắẾếỐốỚớ"},
+    {'\u0301', "ƯưΑΕΗΙΟΥΩϊαεηιϋο", "ỨứΆΈΉΊΌΎΏΐάέήίΰό"},
+    {'\u0301', "υωϒГКгк", "ύώϓЃЌѓќ"},
+    {'\u0302', "AEIOUaeiouCcGgHh", "ÂÊÎÔÛâêîôûĈĉĜĝĤĥ"}, // circumflex
+    {'\u0302', "JjSsWwYyZzẠạẸẹỌọ", "ĴĵŜŝŴŵŶŷẐẑẬậỆệỘộ"},
+    {'\u0303', "ANOanoIiUuVvÂâĂă", "ÃÑÕãñõĨĩŨũṼṽẪẫẴẵ"}, // tilde
+    {'\u0303', "EeÊêÔôƠơƯưYy", "ẼẽỄễỖỗỠỡỮữỸỹ"},
+    {'\u0304', "AaEeIiOoUuÜüÄäȦȧ", "ĀāĒēĪīŌōŪūǕǖǞǟǠǡ"}, // macron
+    {'\u0304', "ÆæǪǫÖöÕõȮȯYyGgḶḷ", "ǢǣǬǭȪȫȬȭȰȱȲȳḠḡḸḹ"},
+    {'\u0304', "ṚṛИиУу", "ṜṝӢӣӮӯ"},
+    {'\u0306', "AaEeGgIiOoUuȨȩẠạ", "ĂăĔĕĞğĬĭŎŏŬŭḜḝẶặ"}, // breve
+    {'\u0306', "УИиуЖжАаЕе", "ЎЙйўӁӂӐӑӖӗ"},
+    {'\u0307', "CcEeGgIZzAaOoBbD", "ĊċĖėĠġİŻżȦȧȮȯḂḃḊ"}, // dot above
+    {'\u0307', "dFfHhMmNnPpRrSsŚ", "ḋḞḟḢḣṀṁṄṅṖṗṘṙṠṡṤ"},
+    {'\u0307', "śŠšṢṣTtWwXxYyſ", "ṥṦṧṨṩṪṫẆẇẊẋẎẏẛ"},
+    {'\u0308', "AEIOUaeiouyYHhÕõ", "ÄËÏÖÜäëïöüÿŸḦḧṎṏ"}, // diaeresis
+    {'\u0308', "ŪūWwXxtΙΥιυϒЕІеі", "ṺṻẄẅẌẍẗΪΫϊϋϔЁЇёї"},
+    {'\u0308', "АаӘәЖжЗзИиОоӨөЭэ", "ӒӓӚӛӜӝӞӟӤӥӦӧӪӫӬӭ"},
+    {'\u0308', "УуЧчЫы", "ӰӱӴӵӸӹ"},
+    {'\u0309', "AaÂâĂăEeÊêIiOoÔô", "ẢảẨẩẲẳẺẻỂểỈỉỎỏỔổ"}, // hook above
+    {'\u0309', "ƠơUuƯưYy", "ỞởỦủỬửỶỷ"},
+    {'\u030A', "AaUuwy", "ÅåŮůẘẙ"},                     // ring
+    {'\u030B', "OoUuУу", "ŐőŰűӲӳ"},                     // double acute
+    {'\u030C', "CcDdEeLlNnRrSsTt", "ČčĎďĚěĽľŇňŘřŠšŤť"}, // caron
+    {'\u030C', "ZzAaIiOoUuÜüGgKk", "ŽžǍǎǏǐǑǒǓǔǙǚǦǧǨǩ"},
+    {'\u030C', "ƷʒjHh", "ǮǯǰȞȟ"},
+    {'\u030F', "AaEeIiOoRrUuѴѵ", "ȀȁȄȅȈȉȌȍȐȑȔȕѶѷ"},     // double grave
+    {'\u0311', "AaEeIiOoRrUu", "ȂȃȆȇȊȋȎȏȒȓȖȗ"},         // inverted breve
+    {'\u031B', "OoUu", "ƠơƯư"},                         // horn
+    {'\u0323', "BbDdHhKkLlMmNnRr", "ḄḅḌḍḤḥḲḳḶḷṂṃṆṇṚṛ"}, // dot below
+    {'\u0323', "SsTtVvWwZzAaEeIi", "ṢṣṬṭṾṿẈẉẒẓẠạẸẹỊị"},
+    {'\u0323', "OoƠơUuƯưYy", "ỌọỢợỤụỰựỴỵ"},
+    {'\u0324', "Uu", "Ṳṳ"},                             // diaeresis below
+    {'\u0325', "Aa", "Ḁḁ"},                             // ring below
+    {'\u0326', "SsTt", "ȘșȚț"},                         // comma below
+    {'\u0327', "CcGgKkLlNnRrSsTt", "ÇçĢģĶķĻļŅņŖŗŞşŢţ"}, // cedilla
+    {'\u0327', "EeDdHh", "ȨȩḐḑḨḩ"},
+    {'\u0328', "AaEeIiUuOo", "ĄąĘęĮįŲųǪǫ"},             // ogonek
+    {'\u032D', "DdEeLlNnTtUu", "ḒḓḘḙḼḽṊṋṰṱṶṷ"},         // circumflex below
+    {'\u032E', "Hh", "Ḫḫ"},                             // breve below
+    {'\u0330', "EeIiUu", "ḚḛḬḭṴṵ"},                     // tilde below
+    {'\u0331', "BbDdKkLlNnRrTtZz", "ḆḇḎḏḴḵḺḻṈṉṞṟṮṯẔẕ"}, // macron below
+    {'\u0331', "h", "ẖ"},
+}
+
+//
+// Maps a letter and a combining accent to the precomposed letter, and back.
+// They're made from compositions.
+//
+var composed, decomposed = func() (map[[2]rune]rune, map[rune][2]rune) {
+    comp, decomp := map[[2]rune]rune{}, map[rune][2]rune{}
+    for _, row := range compositions {
+        letters, precomposed := []rune(row.letters), []rune(row.precomposed)
+        for i := range letters {
+            comp[[2]rune{letters[i], row.accent}] = precomposed[i]
+            decomp[precomposed[i]] = [2]rune{letters[i], row.accent}
+        }
+    }
+    return comp, decomp
+}()
+
+//
+// Returns s in NFC, as far as the compositions table goes: each letter
+// followed by a combining accent it combines with is replaced by the
+// precomposed letter. Anything else is left as it is.
+//
+func nfc(s string) string {
+    var result []rune
+    for _, r := range s {
+        if n := len(result); n > 0 {
+            if c, ok := composed[[2]rune{result[n-1], r}]; ok {
+                result[n-1] = c
+                continue
+            }
+        }
+        result = append(result, r)
+    }
+    return string(result)
+}
+
+//
+// Returns s in NFD, as far as the compositions table goes: each precomposed
+// letter is replaced by its letter and combining accents.
+//
+func nfd(s string) string {
+    var result []rune
+    for _, r := range s {
+        result = appendDecomposed(result, r)
+    }
+    return string(result)
+}
+
+//
+// Appends r to runes, decomposed. A letter with two accents decomposes to a
+// letter with one accent, which is decomposed again.
+//
+func appendDecomposed(runes []rune, r rune) []rune {
+    if d, ok := decomposed[r]; ok {
+        return append(appendDecomposed(runes, d[0]), d[1])
+    }
+    return append(runes, r)
+}
+
+//
+// Returns s with the accents removed from its letters, e.g. "Zoë" becomes
+// "Zoe". Both precomposed letters and combining accents are handled.
+// Letters that aren't an accented form of another letter, like ø and ł,
+// are left alone.
+//
+func removeAccents(s string) string {
+    var result []rune
+    for _, r := range nfd(s) {
+        if !unicode.Is(unicode.Mn, r) { // Mn: combining marks
+            result = append(result, r)
+        }
+    }
+    return string(result)
+}
+
+//
+// Case folding: converts r to a form in which upper and lower case are the
+// same. Converting to upper case and then lower case handles letters like
+// Greek final sigma ς, which is a lower case σ but doesn't equal it.
+//
+// This is simple case folding, one rune at a time, so it can't fold a letter
+// to more than one letter: German ß doesn't equal "ss", for example.
+//
+func foldRune(r rune) rune {
+    return unicode.ToLower(unicode.ToUpper(r))
+}
@@ -0,0 +1,551 @@
+// extsort.go
+
+//
+// External merge sort: sorting a file that's too big to fit in memory.
+//
+// The records (lines or CSV rows) are read in chunks that fit in the memory
+// budget. Each chunk is sorted in memory and written ("spilled") to its own
+// temporary file. Then the sorted chunks are merged: the smallest record at
+// the front of all the chunks is written out, and replaced by the next
+// record from the same chunk, over and over until every chunk is used up.
+//
+//    input --> [chunk 1] [chunk 2] ... [chunk k] --> sort each --> spill
+//
+//    spill 1 --\
+//    spill 2 ---+--> k-way merge --> output
+//    spill k --/
+//
+// Finding the smallest of the k front records quickly is a job for a heap
+// (a priority queue): container/heap finds it in O(log k) time.
+//
+// Only one chunk, plus one record from each spilled chunk, is in memory at a
+// time, so files much bigger than memory can be sorted.
+//
+// Each chunk being merged needs an open file, and the operating system
+// limits how many a program can have open (often to 1024). So at most
+// MaxFanIn chunks are merged at once. If there are more, they're merged in
+// passes: each group of MaxFanIn chunks is merged into a bigger sorted chunk
+// in a new temporary file, until there are few enough to merge into the
+// output.
+//
+
+package main
+
+import (
+    "bufio"
+    "cmp"
+    "container/heap"
+    "encoding/csv"
+    "io"
+    "os"
+    "slices"
+    "strconv"
+    "strings"
+)
+
+//
+// Options for ExternalSort. The zero value sorts lines byte-wise with the
+// default memory budget.
+//
+type Options struct {
+    // The input and output are CSV, rather than lines of text.
+    CSV bool
+
+    // The first record is a header row, which is written first, unsorted.
+    Header bool
+
+    // Returns the key a record is sorted by. A line is a record with one
+    // field. If Key is nil, the whole first field is the key.
+    Key func(record []string) string
+
+    // Compares two keys. If Compare is nil, strings.Compare is used.
+    Compare func(a, b string) int
+
+    // Roughly how many bytes of records to sort in memory at once. If it's
+    // 0, DefaultMemoryBudget is used.
+    MemoryBudget int
+
+    // The directory for the temporary chunk files. If it's "", the
+    // system's temporary directory is used.
+    TempDir string
+
+    // The most chunk files to merge at once (at least 2). If it's 0,
+    // DefaultMaxFanIn is used.
+    MaxFanIn int
+}
+
+const (
+    DefaultMemoryBudget = 64 << 20 // 64 MiB
+    DefaultMaxFanIn     = 128
+)
+
+//
+// Some numbers about a sort.
+//
+type Stats struct {
+    Records int // number of records sorted, not counting the header
+    Chunks  int // number of chunks spilled to temporary files
+    Passes  int // number of merge passes, counting the one into the output
+}
+
+//
+// Returns a Key function that uses field i (counting from 0) of a record. A
+// record that doesn't have field i gets the key "", like a missing field in
+// the Unix sort command.
+//
+func Field(i int) func([]string) string {
+    return func(record []string) string {
+        if i < 0 || i >= len(record) {
+            return ""
+        }
+        return record[i]
+    }
+}
+
+//
+// Compares a and b as numbers if they both are numbers. Numbers come before
+// non-numbers, and non-numbers are compared as strings.
+//
+func CompareNumeric(a, b string) int {
+    x, errA := strconv.ParseFloat(strings.TrimSpace(a), 64)
+    y, errB := strconv.ParseFloat(strings.TrimSpace(b), 64)
+    switch {
+    case errA == nil && errB == nil : return cmp.Compare(x, y)
+    case errA == nil                : return -1
+    case errB == nil                : return 1
+    default                         : return strings.Compare(a, b)
+    }
+}
+
+//
+// A record and its key. The key is extracted once, when the record is read,
+// rather than every time the record is compared.
+//
+type item struct {
+    record []string
+    key    string
+}
+
+//
+// Reading and writing records, as either lines or CSV rows.
+//
+type recordReader interface {
+    Read() ([]string, error) // returns io.EOF after the last record
+}
+
+type recordWriter interface {
+    Write(record []string) error
+    Flush() error
+}
+
+type lineReader struct {
+    r *bufio.Reader
+}
+
+//
+// Returns the next line, without its "\n" or "\r\n". The last line doesn't
+// need to end with "\n".
+//
+func (lr lineReader) Read() ([]string, error) {
+    line, err := lr.r.ReadString('\n')
+    if err == io.EOF && line == "" {
+        return nil, io.EOF
+    } else if err != nil && err != io.EOF {
+        return nil, err
+    }
+    line = strings.TrimSuffix(line, "\n")
+    line = strings.TrimSuffix(line, "\r")
+    return []string{line}, nil
+}
+
+type lineWriter struct {
+    w *bufio.Writer
+}
+
+func (lw lineWriter) Write(record []string) error {
+    _, err := lw.w.WriteString(record[0] + "\n")
+    return err
+}
+
+func (lw lineWriter) Flush() error {
+    return lw.w.Flush()
+}
+
+type csvWriter struct {
+    w *csv.Writer
+}
+
+func (cw csvWriter) Write(record []string) error {
+    return cw.w.Write(record)
+}
+
+func (cw csvWriter) Flush() error {
+    cw.w.Flush()
+    return cw.w.Error()
+}
+
+//
+// newReader and newWriter buffer r and w with a buffer of size bytes (see
+// bufferSize). csv.NewReader and csv.NewWriter use a *bufio.Reader or
+// *bufio.Writer as it is, rather than adding another buffer.
+//
+func (opts *Options) newReader(r io.Reader, size int) recordReader {
+    br := bufio.NewReaderSize(r, size)
+    if opts.CSV {
+        cr := csv.NewReader(br)
+        cr.FieldsPerRecord = -1 // rows can have different numbers of fields
+        return cr
+    }
+    return lineReader{br}
+}
+
+func (opts *Options) newWriter(w io.Writer, size int) recordWriter {
+    bw := bufio.NewWriterSize(w, size)
+    if opts.CSV {
+        return csvWriter{csv.NewWriter(bw)}
+    }
+    return lineWriter{bw}
+}
+
+//
+// Returns the buffer size for each of n files open at once, sharing budget
+// bytes between them. Buffers smaller than 4 KiB make too many system calls,
+// and ones bigger than 1 MiB aren't any faster.
+//
+func bufferSize(budget, n int) int {
+    return min(max(budget/n, 4<<10), 1<<20)
+}
+
+func (opts *Options) key(record []string) string {
+    if opts.Key == nil {
+        return record[0]
+    }
+    return opts.Key(record)
+}
+
+func (opts *Options) compare(a, b string) int {
+    if opts.Compare == nil {
+        return strings.Compare(a, b)
+    }
+    return opts.Compare(a, b)
+}
+
+//
+// Roughly how many bytes of memory a record uses: its characters, plus a
+// string header for each field, plus the slice header and the item.
+//
+func recordSize(record []string) int {
+    size := 64
+    for _, field := range record {
+        size += len(field) + 16
+    }
+    return size
+}
+
+//
+// Reads records from in, and writes them to out sorted by key. The sort is
+// stable: records with equal keys are written in the same order they were
+// read.
+//
+// The temporary files are removed before ExternalSort returns, even if
+// there's an error.
+//
+func ExternalSort(in io.Reader, out io.Writer, opts Options) (Stats, error) {
+    var stats Stats
+    budget := opts.MemoryBudget
+    if budget <= 0 {
+        budget = DefaultMemoryBudget
+    }
+    reader := opts.newReader(in, bufferSize(budget, 2))
+    writer := opts.newWriter(out, bufferSize(budget, 2))
+
+    if opts.Header {
+        header, err := reader.Read()
+        if err == io.EOF {
+            return stats, writer.Flush()
+        } else if err != nil {
+            return stats, err
+        }
+        if err := writer.Write(header); err != nil {
+            return stats, err
+        }
+    }
+
+    var spills []string
+    defer func() {
+        for _, name := range spills {
+            os.Remove(name)
+        }
+    }()
+
+    //
+    // Read and sort chunks until the input runs out. If everything fits in
+    // one chunk, there's no need for temporary files: the chunk is written
+    // straight to out.
+    //
+    for {
+        chunk, eof, err := opts.readChunk(reader, budget)
+        if err != nil {
+            return stats, err
+        }
+        stats.Records += len(chunk)
+        slices.SortStableFunc(chunk, func(a, b item) int {
+            return opts.compare(a.key, b.key)
+        })
+        if eof && len(spills) == 0 {
+            for _, it := range chunk {
+                if err := writer.Write(it.record); err != nil {
+                    return stats, err
+                }
+            }
+            return stats, writer.Flush()
+        }
+        if len(chunk) > 0 {
+            name, err := opts.spill(chunk, budget)
+            if name != "" {
+                spills = append(spills, name)
+            }
+            if err != nil {
+                return stats, err
+            }
+            stats.Chunks++
+        }
+        if eof {
+            break
+        }
+    }
+
+    return stats, opts.merge(spills, writer, budget, &stats)
+}
+
+//
+// Reads records until they use up budget bytes or the input ends. eof is
+// true if the input ended. A chunk always has at least one record, unless
+// the input is empty, so a record bigger than the whole budget still gets
+// sorted.
+//
+func (opts *Options) readChunk(reader recordReader, budget int) (chunk []item, eof bool, err error) {
+    size := 0
+    for size < budget {
+        record, err := reader.Read()
+        if err == io.EOF {
+            return chunk, true, nil
+        } else if err != nil {
+            return chunk, false, err
+        }
+        chunk = append(chunk, item{record, opts.key(record)})
+        size += recordSize(record)
+    }
+    return chunk, false, nil
+}
+
+//
+// Writes a sorted chunk to a new temporary file, and returns its name.
+//
+func (opts *Options) spill(chunk []item, budget int) (name string, err error) {
+    f, err := os.CreateTemp(opts.TempDir, "extsort-*")
+    if err != nil {
+        return "", err
+    }
+    defer func() {
+        if closeErr := f.Close(); err == nil {
+            err = closeErr
+        }
+    }()
+    writer := opts.newWriter(f, bufferSize(budget, 1))
+    for _, it := range chunk {
+        if err := writer.Write(it.record); err != nil {
+            return f.Name(), err
+        }
+    }
+    return f.Name(), writer.Flush()
+}
+
+//
+// The front record of each chunk being merged. chunk is the chunk's number,
+// which breaks ties between equal keys: the record from the earlier chunk
+// was read first, so it goes first, which keeps the sort stable.
+//
+type mergeItem struct {
+    item
+    chunk int
+}
+
+//
+// A min-heap of mergeItems, for container/heap.
+//
+type mergeHeap struct {
+    items   []mergeItem
+    compare func(a, b string) int
+}
+
+func (h *mergeHeap) Len() int {
+    return len(h.items)
+}
+
+func (h *mergeHeap) Less(i, j int) bool {
+    a, b := h.items[i], h.items[j]
+    if result := h.compare(a.key, b.key); result != 0 {
+        return result < 0
+    }
+    return a.chunk < b.chunk
+}
+
+func (h *mergeHeap) Swap(i, j int) {
+    h.items[i], h.items[j] = h.items[j], h.items[i]
+}
+
+func (h *mergeHeap) Push(x any) {
+    h.items = append(h.items, x.(mergeItem))
+}
+
+func (h *mergeHeap) Pop() any {
+    n := len(h.items)
+    x := h.items[n-1]
+    h.items = h.items[:n-1]
+    return x
+}
+
+//
+// Merges the sorted chunk files into writer, at most MaxFanIn at a time.
+//
+// Each pass merges consecutive groups of chunks, so the chunks stay in the
+// order they were read, and the merge stays stable. A group's chunk files
+// are removed as soon as it's merged, so the temporary files never take up
+// much more than twice the size of the input.
+//
+func (opts *Options) merge(spills []string, writer recordWriter, budget int, stats *Stats) error {
+    fanIn := opts.MaxFanIn
+    if fanIn <= 0 {
+        fanIn = DefaultMaxFanIn
+    }
+    fanIn = max(fanIn, 2)
+
+    var runs []string // the chunk files made by merging other chunks
+    defer func() {
+        for _, name := range runs {
+            os.Remove(name)
+        }
+    }()
+
+    for len(spills) > fanIn {
+        var merged []string
+        for start := 0; start < len(spills); start += fanIn {
+            group := spills[start:min(start+fanIn, len(spills))]
+            if len(group) == 1 {
+                merged = append(merged, group[0])
+                continue
+            }
+            name, err := opts.mergeToFile(group, budget)
+            if name != "" {
+                runs = append(runs, name)
+            }
+            if err != nil {
+                return err
+            }
+            for _, done := range group {
+                os.Remove(done)
+            }
+            merged = append(merged, name)
+        }
+        spills = merged
+        stats.Passes++
+    }
+    stats.Passes++
+    return opts.mergeFiles(spills, writer, budget)
+}
+
+//
+// Merges the sorted chunk files into a new temporary file, and returns its
+// name.
+//
+func (opts *Options) mergeToFile(spills []string, budget int) (name string, err error) {
+    f, err := os.CreateTemp(opts.TempDir, "extsort-*")
+    if err != nil {
+        return "", err
+    }
+    defer func() {
+        if closeErr := f.Close(); err == nil {
+            err = closeErr
+        }
+    }()
+    writer := opts.newWriter(f, bufferSize(budget, len(spills)+1))
+    return f.Name(), opts.mergeFiles(spills, writer, budget)
+}
+
+//
+// Merges the sorted chunk files into writer, all at once. The memory budget
+// is shared between the files' buffers and writer's.
+//
+func (opts *Options) mergeFiles(spills []string, writer recordWriter, budget int) error {
+    size := bufferSize(budget, len(spills)+1)
+    readers := make([]recordReader, len(spills))
+    for i, name := range spills {
+        f, err := os.Open(name)
+        if err != nil {
+            return err
+        }
+        defer f.Close()
+        readers[i] = opts.newReader(f, size)
+    }
+
+    //
+    // Reads the next record from chunk i, and pushes it on the heap.
+    //
+    h := &mergeHeap{compare: opts.compare}
+    next := func(i int) error {
+        record, err := readers[i].Read()
+        if err == io.EOF {
+            return nil
+        } else if err != nil {
+            return err
+        }
+        heap.Push(h, mergeItem{item{record, opts.key(record)}, i})
+        return nil
+    }
+
+    for i := range readers {
+        if err := next(i); err != nil {
+            return err
+        }
+    }
+    for h.Len() > 0 {
+        smallest := heap.Pop(h).(mergeItem)
+        if err := writer.Write(smallest.record); err != nil {
+            return err
+        }
+        if err := next(smallest.chunk); err != nil {
+            return err
+        }
+    }
+    return writer.Flush()
+}
+
+//
+// Sorts the file named inName into the file named outName. "-" means
+// standard input or standard output.
+//
+func sortFile(inName, outName string, opts Options) (stats Stats, err error) {
+    in, out := os.Stdin, os.Stdout
+    if inName != "-" {
+        f, err := os.Open(inName)
+        if err != nil {
+            return stats, err
+        }
+        defer f.Close()
+        in = f
+    }
+    if outName != "-" {
+        f, err := os.Create(outName)
+        if err != nil {
+            return stats, err
+        }
+        defer func() {
+            if closeErr := f.Close(); err == nil {
+                err = closeErr
+            }
+        }()
+        out = f
+    }
+    return ExternalSort(in, out, opts)
+}
@@ -0,0 +1,209 @@
+// extsort_test.go
+
+//
+// Tests for ExternalSort. Run them like this:
+//
+//    $ go test *.go
+//
+// Every test uses its own temporary directory (from t.TempDir), so it's easy
+// to check that the chunk files are removed.
+//
+
+package main
+
+import (
+    "fmt"
+    "os"
+    "slices"
+    "strings"
+    "testing"
+)
+
+//
+// Sorts input with opts, using tempDir for the chunk files, and returns the
+// output.
+//
+func sortString(input string, opts Options, tempDir string) (string, Stats, error) {
+    opts.TempDir = tempDir
+    var sb strings.Builder
+    stats, err := ExternalSort(strings.NewReader(input), &sb, opts)
+    return sb.String(), stats, err
+}
+
+//
+// Fails the test if there are any files left in dir.
+//
+func checkNoTempFiles(t *testing.T, dir string) {
+    t.Helper()
+    entries, err := os.ReadDir(dir)
+    if err != nil {
+        t.Fatal(err)
+    }
+    for _, entry := range entries {
+        t.Errorf("temporary file %v wasn't removed", entry.Name())
+    }
+}
+
+//
+// Returns n lines of random numbers, with lots of repeats, and the same
+// lines sorted.
+//
+func randomLines(n int) (input, sorted string) {
+    var lines []string
+    seed := uint32(12345)
+    for i := 0; i < n; i++ {
+        seed = seed*1664525 + 1013904223 // a simple random number generator
+        lines = append(lines, fmt.Sprintf("line %v", seed%1000))
+    }
+    input = strings.Join(lines, "\n") + "\n"
+    slices.Sort(lines)
+    return input, strings.Join(lines, "\n") + "\n"
+}
+
+func TestLines(t *testing.T) {
+    tempDir := t.TempDir()
+    tests := []struct {
+        name, input, want string
+    }{
+        {"lines", "pear\napple\nfig\n", "apple\nfig\npear\n"},
+        {"empty", "", ""},
+        {"CRLF, no final newline", "b\r\na", "a\nb\n"},
+        {"blank line", "b\n\na\n", "\na\nb\n"},
+    }
+    for _, tc := range tests {
+        out, stats, err := sortString(tc.input, Options{}, tempDir)
+        if err != nil || out != tc.want || stats.Chunks != 0 {
+            t.Errorf("%v: got %q, %+v, %v, expected %q", tc.name, out, stats, err, tc.want)
+        }
+    }
+    checkNoTempFiles(t, tempDir)
+}
+
+//
+// A tiny memory budget forces lots of chunks. The result must be the same as
+// sorting everything in memory.
+//
+func TestChunks(t *testing.T) {
+    tempDir := t.TempDir()
+    input, want := randomLines(2000)
+
+    out, stats, err := sortString(input, Options{MemoryBudget: 5000}, tempDir)
+    if err != nil || stats.Chunks <= 10 || stats.Records != 2000 || out != want {
+        t.Errorf("budget 5000: %+v, %v, output correct: %v", stats, err, out == want)
+    }
+    out, stats, err = sortString(input, Options{MemoryBudget: 1}, tempDir)
+    if err != nil || stats.Chunks != 2000 || out != want {
+        t.Errorf("one record per chunk: %+v, %v, output correct: %v", stats, err, out == want)
+    }
+    checkNoTempFiles(t, tempDir)
+}
+
+//
+// With more chunks than MaxFanIn, they're merged in several passes, and the
+// intermediate chunk files are removed too.
+//
+func TestMergePasses(t *testing.T) {
+    tempDir := t.TempDir()
+    input, want := randomLines(2000)
+
+    tests := []struct {
+        fanIn, passes int
+    }{
+        {0, 2},    // DefaultMaxFanIn is 128, and 128 < 2000 <= 128^2
+        {2000, 1}, // all at once
+        {1999, 2}, // one chunk is left over after the first pass
+        {4, 6},    // 4^5 < 2000 <= 4^6
+        {1, 11},   // treated as 2, and 2^10 < 2000 <= 2^11
+    }
+    for _, tc := range tests {
+        opts := Options{MemoryBudget: 1, MaxFanIn: tc.fanIn}
+        out, stats, err := sortString(input, opts, tempDir)
+        if err != nil || stats.Chunks != 2000 || stats.Passes != tc.passes || out != want {
+            t.Errorf("fan-in %v: %+v, %v, expected %v passes, output correct: %v",
+                     tc.fanIn, stats, err, tc.passes, out == want)
+        }
+    }
+    checkNoTempFiles(t, tempDir)
+}
+
+//
+// CSV, sorted by a field, with a header.
+//
+func TestCSV(t *testing.T) {
+    tempDir := t.TempDir()
+    csvInput := "name,age\nBob,20\n\"Smith, Zia\",40\nBarb,3\nAsa,100\n"
+    byAge := Options{CSV: true, Header: true, Key: Field(1), MemoryBudget: 100}
+    numeric := byAge
+    numeric.Compare = CompareNumeric
+
+    tests := []struct {
+        name  string
+        input string
+        opts  Options
+        want  string
+    }{
+        {"CSV numeric", csvInput, numeric,
+         "name,age\nBarb,3\nBob,20\n\"Smith, Zia\",40\nAsa,100\n"},
+        {"CSV string", csvInput, byAge,
+         "name,age\nAsa,100\nBob,20\nBarb,3\n\"Smith, Zia\",40\n"},
+        {"CSV header only", "a,b\n", byAge, "a,b\n"},
+        {"CSV missing field", "x,2\ny\nz,1\n", Options{CSV: true, Key: Field(1)}, "y\nz,1\nx,2\n"},
+    }
+    for _, tc := range tests {
+        if out, _, err := sortString(tc.input, tc.opts, tempDir); err != nil || out != tc.want {
+            t.Errorf("%v: got %q, %v, expected %q", tc.name, out, err, tc.want)
+        }
+    }
+    if _, _, err := sortString("a,\"b\n", Options{CSV: true}, tempDir); err == nil {
+        t.Errorf("no error for bad CSV")
+    }
+    checkNoTempFiles(t, tempDir)
+}
+
+//
+// Records with equal keys stay in their original order, even when they're
+// in different chunks.
+//
+func TestStable(t *testing.T) {
+    tempDir := t.TempDir()
+    var records []string
+    for i := 0; i < 300; i++ {
+        records = append(records, fmt.Sprintf("%v,%v", i%3, i))
+    }
+
+    // A fan-in of 3 needs several passes, and leaves a group of fewer than
+    // 3 chunks at the end of some of them.
+    for _, fanIn := range []int{0, 3} {
+        opts := Options{CSV: true, MemoryBudget: 2000, MaxFanIn: fanIn}
+        out, stats, err := sortString(strings.Join(records, "\n"), opts, tempDir)
+        if err != nil || stats.Chunks <= 1 {
+            t.Fatalf("fan-in %v: %+v, %v, expected more than one chunk", fanIn, stats, err)
+        }
+        for i, line := range strings.Split(strings.TrimSpace(out), "\n") {
+            group, n := i/100, i%100
+            if want := fmt.Sprintf("%v,%v", group, 3*n+group); line != want {
+                t.Fatalf("fan-in %v: line %v is %q, expected %q", fanIn, i, line, want)
+            }
+        }
+    }
+    checkNoTempFiles(t, tempDir)
+}
+
+func TestCompareNumeric(t *testing.T) {
+    tests := []struct {
+        a, b string
+        want int
+    }{
+        {"9", "10", -1},
+        {"-1.5", "1", -1},
+        {"10", "x", -1},
+        {"x", "10", 1},
+        {"b", "a", 1},
+        {" 7", "7", 0},
+    }
+    for _, tc := range tests {
+        if got := CompareNumeric(tc.a, tc.b); got != tc.want {
+            t.Errorf("CompareNumeric(%q, %q) = %v, expected %v", tc.a, tc.b, got, tc.want)
+        }
+    }
+}
@@ -0,0 +1,71 @@
+// main.go
+
+//
+// Sorts a file that may be too big to fit in memory. go run can't be given
+// the _test.go files, so run it like this:
+//
+//    $ go run $(ls *.go | grep -v _test.go) [options] input [output]
+//
+// e.g. to sort a CSV file with a header by its third column, as numbers,
+// using at most about 100MB of memory:
+//
+//    $ go run $(ls *.go | grep -v _test.go) -csv -header -field 2 -numeric \
+//          -mem 100000000 in.csv out.csv
+//
+// input and output can be "-" for standard input and output; output is
+// standard output if it's left out.
+//
+// Lines are sorted as a whole. CSV rows are sorted by one field, the first
+// unless -field says otherwise, and rows with the same key stay in their
+// original order.
+//
+// The tests are in extsort_test.go. Run them like this:
+//
+//    $ go test *.go
+//
+
+package main
+
+import (
+    "flag"
+    "fmt"
+    "os"
+)
+
+func main() {
+    isCSV := flag.Bool("csv", false, "records are CSV rows, not lines")
+    header := flag.Bool("header", false, "the first record is a header")
+    field := flag.Int("field", -1,
+                      "with -csv, sort by this field (counting from 0) instead of the first one")
+    numeric := flag.Bool("numeric", false, "compare keys as numbers")
+    mem := flag.Int("mem", DefaultMemoryBudget, "memory budget in bytes")
+    tempDir := flag.String("tmp", "", "directory for temporary files")
+    fanIn := flag.Int("fanin", DefaultMaxFanIn, "the most temporary files to merge at once")
+    flag.Parse()
+
+    if flag.NArg() < 1 || flag.NArg() > 2 {
+        fmt.Fprintln(os.Stderr, "usage: extsort [options] input [output]")
+        flag.PrintDefaults()
+        os.Exit(2)
+    }
+    outName := "-"
+    if flag.NArg() == 2 {
+        outName = flag.Arg(1)
+    }
+
+    opts := Options{CSV: *isCSV, Header: *header, MemoryBudget: *mem, TempDir: *tempDir,
+                    MaxFanIn: *fanIn}
+    if *field >= 0 {
+        opts.Key = Field(*field)
+    }
+    if *numeric {
+        opts.Compare = CompareNumeric
+    }
+    stats, err := sortFile(flag.Arg(0), outName, opts)
+    if err != nil {
+        fmt.Fprintln(os.Stderr, "extsort:", err)
+        os.Exit(1)
+    }
+    fmt.Fprintf(os.Stderr, "sorted %v records using %v temporary files and %v merge passes\n",
+                stats.Records, stats.Chunks, stats.Passes)
+}
@@ -3,27 +3,47 @@
 //
 // Demonstrates how to uses goroutines and channels to make generators.
 //
+// The tests are in gen_test.go. Run them like this:
+//
+//    $ go test gen.go gen_test.go
+//
 
 package main
 
 import (
+    "context"
     "fmt"
+    "math"
+    "math/big"
 )
 
 //
 // Returns an int channel
 //
-func counter(n int) chan int {
+// The goroutine stops when ctx is cancelled, even if nobody is reading from
+// the channel. Without ctx, a caller that stops reading early would leave the
+// goroutine blocked on ch <- i forever.
+//
+func counter(ctx context.Context, n int) chan int {
     ch := make(chan int)  // ch is an unbuffered channel
 
     //
     // Launch a goroutine.
     //
     go func() {
+        defer close(ch)
         for i := 0; i < n; i++ {
-            ch <- i // blocks here until i is removed from the channel
+            //
+            // select waits until one of its cases can go ahead. So this
+            // blocks until either i is removed from the channel, or ctx is
+            // cancelled.
+            //
+            select {
+            case ch <- i:
+            case <-ctx.Done():
+                return
+            }
         }
-        close(ch)
     }() // note the () is needed here since this is a function call
 
     //
@@ -32,8 +52,8 @@ func counter(n int) chan int {
     return ch
 }
 
-func TestCounter() {
-    for n := range counter(10) {
+func demoCounter() {
+    for n := range counter(context.Background(), 10) {
         fmt.Println(n)
     }
 }
@@ -41,33 +61,189 @@ func TestCounter() {
 //
 // Generate the Fibonacci numbers one at a time.
 //
-// There is no stopping condition in the go routine. The calling code will
-// contain the the stopping condition.
+// Apart from overflow (see below), there is no stopping condition in the go
+// routine. The calling code will contain the the stopping condition, and
+// cancels ctx when it is done so the goroutine can end. When that happens,
+// the channel is closed.
 //
-func fibgen() chan int {
+// Fibonacci numbers grow quickly, and the 93rd one is too big for a 64-bit
+// int. Go doesn't report integer overflow, it just wraps around to a
+// negative number. So fibgen checks before adding, and closes the channel
+// after the last Fibonacci number that fits. Use bigFibgen to keep going.
+//
+func fibgen(ctx context.Context) chan int {
     ch := make(chan int) // unbuffered channel
     go func() {
+        defer close(ch)
         a, b := 1, 1
         for {            // infinite loop
-            ch <- a      // blocks here until a is received
+            select {
+            case ch <- a: // blocks here until a is received ...
+            case <-ctx.Done(): // ... or ctx is cancelled
+                return
+            }
+            if a > math.MaxInt-b { // a+b is too big, so b is the last one
+                select {
+                case ch <- b:
+                case <-ctx.Done():
+                }
+                return
+            }
             a, b = b, a+b
         }
     }()
     return ch
 }
 
-func TestFib() {
+//
+// Like fibgen, but generates *big.Int values, which have no maximum size. So
+// this channel never closes by itself; the caller must cancel ctx.
+//
+func bigFibgen(ctx context.Context) chan *big.Int {
+    return bigRecurrence(ctx, []int64{1, 1}, []int64{1, 1})
+}
+
+//
+// Generates the terms of a linear recurrence, where each term is a sum of
+// multiples of the terms before it:
+//
+//    x[n] = coeffs[0]*x[n-1] + coeffs[1]*x[n-2] + ... + coeffs[k-1]*x[n-k]
+//
+// The first k terms are given by initial, so len(initial) must equal
+// len(coeffs). For example, the Fibonacci numbers are
+// recurrence(ctx, []int{1, 1}, []int{1, 1}).
+//
+// Like fibgen, the channel is closed after the last term that fits in an
+// int.
+//
+func recurrence(ctx context.Context, coeffs []int, initial []int) chan int {
+    if len(coeffs) != len(initial) {
+        panic("recurrence: need one initial value per coefficient")
+    }
+    ch := make(chan int)
+    go func() {
+        defer close(ch)
+        //
+        // window holds the terms that still need to be sent, oldest first.
+        // After an overflow no more terms are added, but the ones already in
+        // window are still sent.
+        //
+        window := append([]int{}, initial...)
+        overflow := false
+        for len(window) > 0 {
+            select {
+            case ch <- window[0]:
+            case <-ctx.Done():
+                return
+            }
+            if !overflow {
+                next, ok := nextTerm(coeffs, window)
+                if ok {
+                    window = append(window, next)
+                } else {
+                    overflow = true
+                }
+            }
+            window = window[1:]
+        }
+    }()
+    return ch
+}
+
+//
+// Returns the next term of a recurrence, given the last len(coeffs) terms
+// (oldest first). ok is false if the result doesn't fit in an int.
+//
+func nextTerm(coeffs []int, window []int) (next int, ok bool) {
+    k := len(coeffs)
+    for i, c := range coeffs {
+        x := window[k-1-i]
+        // Check if c*x overflows by seeing if dividing gives c back. That
+        // doesn't work for math.MinInt / -1, which also overflows.
+        product := c * x
+        if x != 0 && (product/x != c || (x == -1 && c == math.MinInt)) {
+            return 0, false
+        }
+        // Adding a positive number must make next bigger, and adding a
+        // negative one must make it smaller. If not, it wrapped around.
+        sum := next + product
+        if (product > 0 && sum < next) || (product < 0 && sum > next) {
+            return 0, false
+        }
+        next = sum
+    }
+    return next, true
+}
+
+//
+// Like recurrence, but generates *big.Int values, so it never overflows and
+// never closes its channel by itself.
+//
+func bigRecurrence(ctx context.Context, coeffs []int64, initial []int64) chan *big.Int {
+    if len(coeffs) != len(initial) {
+        panic("bigRecurrence: need one initial value per coefficient")
+    }
+    ch := make(chan *big.Int)
+    go func() {
+        defer close(ch)
+        k := len(coeffs)
+        bigCoeffs := make([]*big.Int, k)
+        window := make([]*big.Int, k)
+        for i := range coeffs {
+            bigCoeffs[i] = big.NewInt(coeffs[i])
+            window[i] = big.NewInt(initial[i])
+        }
+        for {
+            select {
+            case ch <- window[0]: // window[0] is never changed, so it's safe to send
+            case <-ctx.Done():
+                return
+            }
+            next := new(big.Int)
+            for i, c := range bigCoeffs {
+                next.Add(next, new(big.Int).Mul(c, window[k-1-i]))
+            }
+            window = append(window[1:], next)
+        }
+    }()
+    return ch
+}
+
+//
+// Some well-known linear recurrences.
+//
+func lucas(ctx context.Context) chan int {      // 2, 1, 3, 4, 7, 11, ...
+    return recurrence(ctx, []int{1, 1}, []int{2, 1})
+}
+
+func pell(ctx context.Context) chan int {       // 0, 1, 2, 5, 12, 29, ...
+    return recurrence(ctx, []int{2, 1}, []int{0, 1})
+}
+
+func tribonacci(ctx context.Context) chan int { // 0, 0, 1, 1, 2, 4, 7, ...
+    return recurrence(ctx, []int{1, 1, 1}, []int{0, 0, 1})
+}
+
+func demoFib() {
+    //
+    // cancel must be called when we're done with the generator, otherwise
+    // its goroutine never ends. defer makes sure it's called when demoFib
+    // returns.
+    //
+    ctx, cancel := context.WithCancel(context.Background())
+    defer cancel()
+
     //
     // Every time <-nextFib is called, the next Fibonacci number is taken from
     // the channel.
     //
-    nextFib := fibgen()
+    nextFib := fibgen(ctx)
     for i := 0; i < 10; i++ {
         fmt.Println(<-nextFib)
     }
 }
 
 func main() {
-    TestCounter()
-    // TestFib()
+    demoCounter()
+    // demoFib()
 }
@@ -0,0 +1,138 @@
+// gen_test.go
+
+//
+// Tests for the generators in gen.go. Run them like this:
+//
+//    $ go test gen.go gen_test.go
+//
+
+package main
+
+import (
+    "context"
+    "fmt"
+    "math/big"
+    "runtime"
+    "testing"
+    "time"
+)
+
+func TestCounter(t *testing.T) {
+    var got []int
+    for n := range counter(context.Background(), 5) {
+        got = append(got, n)
+    }
+    if fmt.Sprint(got) != "[0 1 2 3 4]" {
+        t.Errorf("counter(5) = %v, expected [0 1 2 3 4]", got)
+    }
+}
+
+//
+// Checks that fibgen stops before it overflows, that bigFibgen keeps going
+// with the right values, and that the other recurrences start correctly.
+//
+func TestRecurrences(t *testing.T) {
+    ctx, cancel := context.WithCancel(context.Background())
+    defer cancel()
+
+    //
+    // Reading everything from fibgen works, since it stops by itself.
+    //
+    var fibs []int
+    for f := range fibgen(ctx) {
+        fibs = append(fibs, f)
+    }
+    if last := fibs[len(fibs)-1]; len(fibs) != 92 || last != 7540113804746346429 {
+        t.Errorf("fibgen gave %v numbers ending in %v, expected 92 ending in 7540113804746346429",
+                 len(fibs), last)
+    }
+
+    nextBig := bigFibgen(ctx)
+    var f *big.Int
+    for i := 0; i < 100; i++ {
+        f = <-nextBig
+        if i < len(fibs) && f.Cmp(big.NewInt(int64(fibs[i]))) != 0 {
+            t.Errorf("bigFibgen number %v = %v, expected %v", i, f, fibs[i])
+        }
+    }
+    if f.String() != "354224848179261915075" {
+        t.Errorf("bigFibgen 100th = %v, expected 354224848179261915075", f)
+    }
+
+    // Returns the first n values from ch as a string.
+    first := func(ch chan int, n int) string {
+        var result []int
+        for i := 0; i < n; i++ {
+            result = append(result, <-ch)
+        }
+        return fmt.Sprint(result)
+    }
+    tests := []struct {
+        name     string
+        ch       chan int
+        n        int
+        expected string
+    }{
+        {"lucas", lucas(ctx), 8, "[2 1 3 4 7 11 18 29]"},
+        {"pell", pell(ctx), 8, "[0 1 2 5 12 29 70 169]"},
+        {"tribonacci", tribonacci(ctx), 9, "[0 0 1 1 2 4 7 13 24]"},
+        {"alternating", recurrence(ctx, []int{-1}, []int{1}), 4, "[1 -1 1 -1]"},
+        {"powers of 3", recurrence(ctx, []int{3}, []int{1}), 5, "[1 3 9 27 81]"},
+    }
+    for _, test := range tests {
+        if got := first(test.ch, test.n); got != test.expected {
+            t.Errorf("%v = %v, expected %v", test.name, got, test.expected)
+        }
+    }
+
+    //
+    // Every recurrence channel must close after its last term that fits.
+    //
+    count := 0
+    prev := 0
+    for p := range pell(ctx) {
+        if p < prev {
+            t.Errorf("pell overflowed after %v terms: %v < %v", count, p, prev)
+        }
+        prev = p
+        count++
+    }
+    if count <= 40 {
+        t.Errorf("pell stopped after %v terms, expected more than 40", count)
+    }
+}
+
+//
+// Starts a bunch of generators, reads only part of what they generate, and
+// then cancels them. If the generators' goroutines all end, then the number
+// of running goroutines goes back to what it was at the start.
+//
+func TestNoLeaks(t *testing.T) {
+    before := runtime.NumGoroutine()
+
+    ctx, cancel := context.WithCancel(context.Background())
+    for i := 0; i < 100; i++ {
+        c := counter(ctx, 1000)
+        f := fibgen(ctx)
+        for j := 0; j < 5; j++ {
+            <-c
+            <-f
+        }
+    }
+    during := runtime.NumGoroutine()
+    cancel()
+
+    //
+    // Goroutines don't end the instant ctx is cancelled, so wait a little
+    // while for them to finish.
+    //
+    after := runtime.NumGoroutine()
+    for start := time.Now(); after > before && time.Since(start) < time.Second; {
+        time.Sleep(10 * time.Millisecond)
+        after = runtime.NumGoroutine()
+    }
+    if after > before {
+        t.Errorf("goroutines leaked: %v before, %v during, %v after cancel",
+                 before, during, after)
+    }
+}
@@ -0,0 +1,276 @@
+// broker.go
+
+//
+// A publish/subscribe message broker. Publishers send messages to a named
+// topic, and every subscriber to that topic gets its own copy. Any number
+// of goroutines can publish and subscribe at the same time.
+//
+//    b := NewBroker[string]()
+//    sub, _ := b.Subscribe("news", 10, DropOldest)
+//    b.Publish(ctx, "news", "hello")
+//    fmt.Println(<-sub.C()) // hello
+//    b.Close()              // closes sub.C()
+//
+// Each subscriber has a buffered channel. If a subscriber is slow and its
+// buffer fills up, its DropPolicy decides what happens to the next message:
+// drop the oldest buffered message to make room, drop the new message, or
+// block the publisher until there's room.
+//
+
+package main
+
+import (
+    "context"
+    "errors"
+    "sync"
+    "sync/atomic"
+)
+
+var ErrBrokerClosed = errors.New("broker: broker is closed")
+
+//
+// What to do with a message when a subscriber's buffer is full.
+//
+type DropPolicy int
+
+const (
+    DropOldest DropPolicy = iota // throw away the oldest buffered message
+    DropNewest                   // throw away the new message
+    Block                        // wait until the subscriber makes room
+)
+
+func (p DropPolicy) String() string {
+    switch p {
+    case DropOldest : return "DropOldest"
+    case DropNewest : return "DropNewest"
+    case Block      : return "Block"
+    default         : return "unknown"
+    }
+}
+
+type Broker[T any] struct {
+    mu     sync.Mutex // protects topics and closed
+    topics map[string]map[*Subscription[T]]bool
+    closed bool
+}
+
+//
+// A Subscription is one subscriber's connection to a topic.
+//
+// Sending to a closed channel panics, so sem makes sure a message is never
+// sent to ch at the same time as ch is being closed. sem works like a mutex
+// (a send takes it and a receive lets it go), but unlike sync.Mutex, waiting
+// for it can be cut short with select. A Block publisher holds sem while it
+// waits for room, so another publisher waiting for sem gives up when its ctx
+// is cancelled instead of hanging. Unsubscribe first closes done, which
+// wakes up every publisher and makes the one holding sem let go of it.
+//
+type Subscription[T any] struct {
+    broker   *Broker[T]
+    topic    string
+    policy   DropPolicy
+    ch       chan T
+    sem      chan struct{} // protects ch and closed
+    closed   bool
+    done     chan struct{}
+    doneOnce sync.Once
+    dropped  atomic.Int64
+}
+
+func NewBroker[T any]() *Broker[T] {
+    return &Broker[T]{topics: map[string]map[*Subscription[T]]bool{}}
+}
+
+//
+// Subscribes to topic. Messages arrive on the subscription's C() channel,
+// which holds up to buffer messages (at least 1) before policy applies. The
+// error is ErrBrokerClosed if the broker has been closed.
+//
+func (b *Broker[T]) Subscribe(topic string, buffer int, policy DropPolicy) (*Subscription[T], error) {
+    b.mu.Lock()
+    defer b.mu.Unlock()
+    if b.closed {
+        return nil, ErrBrokerClosed
+    }
+    s := &Subscription[T]{
+        broker: b,
+        topic:  topic,
+        policy: policy,
+        ch:     make(chan T, max(buffer, 1)),
+        sem:    make(chan struct{}, 1),
+        done:   make(chan struct{}),
+    }
+    if b.topics[topic] == nil {
+        b.topics[topic] = map[*Subscription[T]]bool{}
+    }
+    b.topics[topic][s] = true
+    return s, nil
+}
+
+//
+// Sends msg to every current subscriber of topic. With the Block policy it
+// may have to wait for slow subscribers; if ctx is cancelled while waiting,
+// it gives up and returns ctx's error. The error is ErrBrokerClosed if the
+// broker has been closed.
+//
+// The broker's lock is only held long enough to copy the list of
+// subscribers, so a blocked publisher doesn't stop other goroutines from
+// subscribing, unsubscribing, or publishing to other subscribers. The Block
+// subscribers are sent msg at the same time, each in its own goroutine, so
+// one slow subscriber doesn't hold up the others.
+//
+// Delivery isn't all or nothing. If ctx is cancelled, the subscribers that
+// already have msg keep it, and the ones that were still being waited for
+// never get it.
+//
+func (b *Broker[T]) Publish(ctx context.Context, topic string, msg T) error {
+    b.mu.Lock()
+    if b.closed {
+        b.mu.Unlock()
+        return ErrBrokerClosed
+    }
+    subs := make([]*Subscription[T], 0, len(b.topics[topic]))
+    for s := range b.topics[topic] {
+        subs = append(subs, s)
+    }
+    b.mu.Unlock()
+
+    var blocking []*Subscription[T]
+    for _, s := range subs {
+        if s.policy == Block {
+            blocking = append(blocking, s)
+        } else if err := s.deliver(ctx, msg); err != nil {
+            return err
+        }
+    }
+    if len(blocking) == 1 {
+        return blocking[0].deliver(ctx, msg)
+    }
+    errs := make(chan error, len(blocking))
+    for _, s := range blocking {
+        go func() { errs <- s.deliver(ctx, msg) }()
+    }
+    var err error
+    for range blocking {
+        if e := <-errs; e != nil {
+            err = e
+        }
+    }
+    return err
+}
+
+//
+// Sends msg to s according to s's DropPolicy. Messages to a subscription
+// that has been closed are silently dropped.
+//
+func (s *Subscription[T]) deliver(ctx context.Context, msg T) error {
+    select {
+    case s.sem <- struct{}{}:
+    case <-s.done:
+        return nil
+    case <-ctx.Done():
+        return ctx.Err()
+    }
+    defer func() { <-s.sem }()
+    if s.closed {
+        return nil
+    }
+    switch s.policy {
+    case DropNewest:
+        select {
+        case s.ch <- msg:
+        default:
+            s.dropped.Add(1)
+        }
+    case DropOldest:
+        for {
+            select {
+            case s.ch <- msg:
+                return nil
+            default:
+            }
+            // The buffer is full, so remove the oldest message. The
+            // subscriber might have read it in the meantime, in which case
+            // there's room now anyway.
+            select {
+            case <-s.ch:
+                s.dropped.Add(1)
+            default:
+            }
+        }
+    case Block:
+        select {
+        case s.ch <- msg:
+        case <-s.done:
+        case <-ctx.Done():
+            return ctx.Err()
+        }
+    }
+    return nil
+}
+
+//
+// Returns the channel that this subscription's messages arrive on. It's
+// closed when the subscription ends.
+//
+func (s *Subscription[T]) C() <-chan T {
+    return s.ch
+}
+
+//
+// Returns the number of messages thrown away because the buffer was full.
+//
+func (s *Subscription[T]) Dropped() int {
+    return int(s.dropped.Load())
+}
+
+//
+// Ends the subscription and closes its channel. Messages still in the
+// buffer can be read before the channel reports it's closed. It's fine to
+// call Unsubscribe more than once.
+//
+func (s *Subscription[T]) Unsubscribe() {
+    b := s.broker
+    b.mu.Lock()
+    delete(b.topics[s.topic], s)
+    if len(b.topics[s.topic]) == 0 {
+        delete(b.topics, s.topic)
+    }
+    b.mu.Unlock()
+    s.close()
+}
+
+func (s *Subscription[T]) close() {
+    s.doneOnce.Do(func() { close(s.done) }) // wakes up a Block publisher
+    s.sem <- struct{}{}
+    defer func() { <-s.sem }()
+    if !s.closed {
+        s.closed = true
+        close(s.ch)
+    }
+}
+
+//
+// Shuts down the broker: every subscription is ended and its channel
+// closed, and later calls to Publish and Subscribe return ErrBrokerClosed.
+//
+func (b *Broker[T]) Close() {
+    b.mu.Lock()
+    if b.closed {
+        b.mu.Unlock()
+        return
+    }
+    b.closed = true
+    var subs []*Subscription[T]
+    for _, topicSubs := range b.topics {
+        for s := range topicSubs {
+            subs = append(subs, s)
+        }
+    }
+    b.topics = map[string]map[*Subscription[T]]bool{}
+    b.mu.Unlock()
+
+    for _, s := range subs {
+        s.close()
+    }
+}
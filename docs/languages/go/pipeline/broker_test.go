@@ -0,0 +1,256 @@
+// broker_test.go
+
+//
+// Tests for the broker. Run them with -race to check for data races.
+//
+
+package main
+
+import (
+    "context"
+    "fmt"
+    "runtime"
+    "sync"
+    "testing"
+    "time"
+)
+
+//
+// Reads everything currently buffered in sub, without waiting.
+//
+func drain(sub *Subscription[int]) []int {
+    var result []int
+    for {
+        select {
+        case x, ok := <-sub.C():
+            if !ok {
+                return result
+            }
+            result = append(result, x)
+        default:
+            return result
+        }
+    }
+}
+
+func TestBroker(t *testing.T) {
+    before := runtime.NumGoroutine()
+    ctx := context.Background()
+
+    //
+    // Every subscriber to a topic gets every message, and subscribers to
+    // other topics get none.
+    //
+    b := NewBroker[int]()
+    a1, _ := b.Subscribe("a", 10, Block)
+    a2, _ := b.Subscribe("a", 10, DropNewest)
+    other, _ := b.Subscribe("b", 10, Block)
+    for i := 0; i < 3; i++ {
+        b.Publish(ctx, "a", i)
+    }
+    b.Publish(ctx, "nobody", 99)
+    if got := fmt.Sprint(drain(a1)); got != "[0 1 2]" {
+        t.Errorf("subscriber 1 got %v", got)
+    }
+    if got := fmt.Sprint(drain(a2)); got != "[0 1 2]" {
+        t.Errorf("subscriber 2 got %v", got)
+    }
+    if got := drain(other); len(got) != 0 {
+        t.Errorf("subscriber to another topic got %v", got)
+    }
+
+    //
+    // Drop policies with a buffer of 2 and 5 unread messages.
+    //
+    newest, _ := b.Subscribe("drop", 2, DropNewest)
+    oldest, _ := b.Subscribe("drop", 2, DropOldest)
+    for i := 0; i < 5; i++ {
+        b.Publish(ctx, "drop", i)
+    }
+    if got := fmt.Sprint(drain(newest)); got != "[0 1]" || newest.Dropped() != 3 {
+        t.Errorf("DropNewest kept %v and dropped %v", got, newest.Dropped())
+    }
+    if got := fmt.Sprint(drain(oldest)); got != "[3 4]" || oldest.Dropped() != 3 {
+        t.Errorf("DropOldest kept %v and dropped %v", got, oldest.Dropped())
+    }
+
+    //
+    // Block makes the publisher wait until the subscriber reads.
+    //
+    blocker, _ := b.Subscribe("block", 1, Block)
+    published := make(chan int, 3)
+    go func() {
+        for i := 0; i < 3; i++ {
+            b.Publish(ctx, "block", i)
+            published <- i
+        }
+        close(published)
+    }()
+    if x := <-published; x != 0 {
+        t.Errorf("first publish: got %v", x)
+    }
+    time.Sleep(10 * time.Millisecond)
+    select {
+    case <-published:
+        t.Errorf("Block didn't wait for the subscriber")
+    default:
+    }
+    var got []int
+    for i := 0; i < 3; i++ {
+        got = append(got, <-blocker.C())
+    }
+    for range published {
+    }
+    if fmt.Sprint(got) != "[0 1 2]" || blocker.Dropped() != 0 {
+        t.Errorf("Block subscriber got %v and dropped %v", got, blocker.Dropped())
+    }
+
+    //
+    // A blocked Publish gives up when its context is cancelled, or when the
+    // subscriber unsubscribes.
+    //
+    b.Publish(ctx, "block", 1) // fills the buffer
+    timeoutCtx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+    err := b.Publish(timeoutCtx, "block", 2)
+    cancel()
+    if err != context.DeadlineExceeded {
+        t.Errorf("blocked publish: error %v, expected %v", err, context.DeadlineExceeded)
+    }
+
+    errs := make(chan error)
+    go func() { errs <- b.Publish(ctx, "block", 3) }()
+    time.Sleep(10 * time.Millisecond)
+    blocker.Unsubscribe()
+    if err := <-errs; err != nil {
+        t.Errorf("publish woken by Unsubscribe: error %v", err)
+    }
+    if got := fmt.Sprint(drain(blocker)); got != "[1]" {
+        t.Errorf("after Unsubscribe, the buffer had %v", got)
+    }
+    if _, ok := <-blocker.C(); ok {
+        t.Errorf("Unsubscribe didn't close the channel")
+    }
+    blocker.Unsubscribe() // a second call must not panic
+    if err := b.Publish(ctx, "block", 4); err != nil {
+        t.Errorf("publish after Unsubscribe: error %v", err)
+    }
+
+    //
+    // Lots of publishers and subscribers at once.
+    //
+    var wg sync.WaitGroup
+    counts := make([]int, 10)
+    for i := range counts {
+        sub, _ := b.Subscribe("busy", 5, Block)
+        wg.Add(1)
+        go func(i int) {
+            defer wg.Done()
+            for range sub.C() {
+                counts[i]++
+            }
+        }(i)
+    }
+    var pubs sync.WaitGroup
+    for p := 0; p < 10; p++ {
+        pubs.Add(1)
+        go func() {
+            defer pubs.Done()
+            for i := 0; i < 100; i++ {
+                b.Publish(ctx, "busy", i)
+            }
+        }()
+    }
+    pubs.Wait()
+
+    //
+    // Close ends every subscription, after which the broker can't be used.
+    //
+    b.Close()
+    wg.Wait()
+    for i, n := range counts {
+        if n != 1000 {
+            t.Errorf("busy subscriber %v got %v messages, expected 1000", i, n)
+        }
+    }
+    if _, ok := <-a1.C(); ok {
+        t.Errorf("Close didn't close the subscribers")
+    }
+    if err := b.Publish(ctx, "a", 1); err != ErrBrokerClosed {
+        t.Errorf("publish after Close: error %v, expected %v", err, ErrBrokerClosed)
+    }
+    if _, err := b.Subscribe("a", 1, Block); err != ErrBrokerClosed {
+        t.Errorf("subscribe after Close: error %v, expected %v", err, ErrBrokerClosed)
+    }
+    b.Close() // a second call must not panic
+    a1.Unsubscribe()
+
+    checkGoroutinesEnd(t, before)
+}
+
+//
+// A Publish waiting for a slow Block subscriber mustn't hold up other
+// publishers or other subscribers.
+//
+func TestBrokerSlowSubscriber(t *testing.T) {
+    before := runtime.NumGoroutine()
+    ctx := context.Background()
+    b := NewBroker[int]()
+    slow, _ := b.Subscribe("t", 1, Block)
+    b.Publish(ctx, "t", 0) // fills slow's buffer
+
+    //
+    // The first Publish waits for slow to make room. A second one, waiting
+    // behind it, still gives up when its context times out.
+    //
+    first := make(chan error)
+    go func() { first <- b.Publish(ctx, "t", 1) }()
+    time.Sleep(10 * time.Millisecond)
+    timeoutCtx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+    done := make(chan error)
+    go func() { done <- b.Publish(timeoutCtx, "t", 2) }()
+    select {
+    case err := <-done:
+        if err != context.DeadlineExceeded {
+            t.Errorf("second publish: error %v, expected %v", err, context.DeadlineExceeded)
+        }
+    case <-time.After(time.Second):
+        t.Errorf("second publish ignored its context")
+        <-done
+    }
+    cancel()
+    if got := <-slow.C(); got != 0 {
+        t.Errorf("slow subscriber got %v, expected 0", got)
+    }
+    if err := <-first; err != nil {
+        t.Errorf("first publish: error %v", err)
+    }
+    if got := drain(slow); fmt.Sprint(got) != "[1]" {
+        t.Errorf("slow subscriber then got %v, expected [1]", got)
+    }
+
+    //
+    // When slow is full, the other subscribers still get the message, even
+    // though Publish times out. The delivery is partial: slow never gets it.
+    //
+    b.Publish(ctx, "t", 3) // fills slow's buffer again
+    fast, _ := b.Subscribe("t", 10, Block)
+    dropper, _ := b.Subscribe("t", 10, DropNewest)
+    timeoutCtx, cancel = context.WithTimeout(ctx, 20*time.Millisecond)
+    err := b.Publish(timeoutCtx, "t", 4)
+    cancel()
+    if err != context.DeadlineExceeded {
+        t.Errorf("publish to a full subscriber: error %v, expected %v", err, context.DeadlineExceeded)
+    }
+    if got := fmt.Sprint(drain(fast)); got != "[4]" {
+        t.Errorf("Block subscriber behind a slow one got %v, expected [4]", got)
+    }
+    if got := fmt.Sprint(drain(dropper)); got != "[4]" {
+        t.Errorf("DropNewest subscriber behind a slow one got %v, expected [4]", got)
+    }
+    if got := fmt.Sprint(drain(slow)); got != "[3]" {
+        t.Errorf("slow subscriber got %v, expected [3]", got)
+    }
+
+    b.Close()
+    checkGoroutinesEnd(t, before)
+}
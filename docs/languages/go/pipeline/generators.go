@@ -0,0 +1,77 @@
+// generators.go
+
+//
+// The generators from ../gen.go, which the other files in this directory
+// build on. Like in gen.go, each generator stops and closes its channel when
+// ctx is cancelled.
+//
+// This directory is one program made of several files, plus tests in the
+// _test.go files, so run it and test it like this:
+//
+//    $ go run $(ls *.go | grep -v _test.go)
+//    $ go test *.go
+//
+
+package main
+
+import (
+    "context"
+    "math"
+)
+
+//
+// Returns a channel that generates 0, 1, 2, ..., n-1.
+//
+func counter(ctx context.Context, n int) chan int {
+    ch := make(chan int)
+    go func() {
+        defer close(ch)
+        for i := 0; i < n; i++ {
+            select {
+            case ch <- i:
+            case <-ctx.Done():
+                return
+            }
+        }
+    }()
+    return ch
+}
+
+//
+// Returns a channel that generates the Fibonacci numbers 1, 1, 2, 3, 5, ...
+// The channel is closed after the last one that fits in an int.
+//
+func fibgen(ctx context.Context) chan int {
+    ch := make(chan int)
+    go func() {
+        defer close(ch)
+        a, b := 1, 1
+        for {
+            select {
+            case ch <- a:
+            case <-ctx.Done():
+                return
+            }
+            if a > math.MaxInt-b { // a+b is too big, so b is the last one
+                select {
+                case ch <- b:
+                case <-ctx.Done():
+                }
+                return
+            }
+            a, b = b, a+b
+        }
+    }()
+    return ch
+}
+
+//
+// Returns a slice of everything received from ch, until it is closed.
+//
+func collect[T any](ch <-chan T) []T {
+    var result []T
+    for x := range ch {
+        result = append(result, x)
+    }
+    return result
+}
@@ -0,0 +1,130 @@
+// iter.go
+
+//
+// Iterator versions of counter and fibgen.
+//
+// Since Go 1.23, a for-range loop can range over a function. An iter.Seq[T]
+// is a function that takes a yield function, and calls yield(x) for each
+// value x it generates. The body of the for-range loop becomes yield, so
+//
+//    for n := range countSeq(10) {
+//        fmt.Println(n)
+//    }
+//
+// prints 0 to 9, just like ranging over counter(ctx, 10). yield returns false
+// if the loop ended early (e.g. with break), and then the iterator must stop.
+//
+// Unlike a channel generator, an iterator needs no goroutine, no channel,
+// and no context: when the loop stops, the iterator function just returns.
+// It's also much faster, since sending a value on a channel means switching
+// between goroutines.
+//
+
+package main
+
+import (
+    "context"
+    "iter"
+    "math"
+)
+
+//
+// Returns an iterator over 0, 1, 2, ..., n-1.
+//
+func countSeq(n int) iter.Seq[int] {
+    return func(yield func(int) bool) {
+        for i := 0; i < n; i++ {
+            if !yield(i) {
+                return
+            }
+        }
+    }
+}
+
+//
+// Returns an iterator over the Fibonacci numbers 1, 1, 2, 3, 5, ... As with
+// fibgen, it stops after the last one that fits in an int.
+//
+func fibSeq() iter.Seq[int] {
+    return func(yield func(int) bool) {
+        a, b := 1, 1
+        for yield(a) {
+            if a > math.MaxInt-b {
+                yield(b)
+                return
+            }
+            a, b = b, a+b
+        }
+    }
+}
+
+//
+// Returns an iterator over the values received from ch. The iterator stops
+// when ch is closed.
+//
+// If the loop stops early, ch's goroutine is still blocked trying to send
+// its next value, so cancel its context afterwards (or use genToSeq, which
+// does that automatically).
+//
+func chanToSeq[T any](ch <-chan T) iter.Seq[T] {
+    return func(yield func(T) bool) {
+        for x := range ch {
+            if !yield(x) {
+                return
+            }
+        }
+    }
+}
+
+//
+// Returns an iterator over the values made by a channel generator like
+// fibgen. Each time the iterator is used it starts the generator with a new
+// context, and cancels that context when the loop ends, so the generator's
+// goroutine never leaks, even if the loop breaks early.
+//
+func genToSeq[T any](gen func(context.Context) chan T) iter.Seq[T] {
+    return func(yield func(T) bool) {
+        ctx, cancel := context.WithCancel(context.Background())
+        defer cancel()
+        for x := range gen(ctx) {
+            if !yield(x) {
+                return
+            }
+        }
+    }
+}
+
+//
+// Returns a channel generator that sends the values of seq, so iterators can
+// be used with the pipeline stages in pipeline.go. The goroutine stops when
+// seq ends or ctx is cancelled.
+//
+func seqToChan[T any](ctx context.Context, seq iter.Seq[T]) <-chan T {
+    out := make(chan T)
+    go func() {
+        defer close(out)
+        for x := range seq {
+            if !send(ctx, out, x) {
+                return // ends the range loop, which stops seq
+            }
+        }
+    }()
+    return out
+}
+
+//
+// Returns the first n values of seq, or all of them if there are fewer.
+//
+func takeSeq[T any](seq iter.Seq[T], n int) []T {
+    var result []T
+    if n <= 0 {
+        return result
+    }
+    for x := range seq {
+        result = append(result, x)
+        if len(result) == n {
+            break
+        }
+    }
+    return result
+}
@@ -0,0 +1,131 @@
+// iter_test.go
+
+//
+// Tests for the iterators and adapters.
+//
+
+package main
+
+import (
+    "context"
+    "iter"
+    "runtime"
+    "slices"
+    "testing"
+)
+
+func TestIter(t *testing.T) {
+    fibs := []int{1, 1, 2, 3, 5, 8, 13, 21, 34, 55}
+
+    before := runtime.NumGoroutine()
+    ctx, cancel := context.WithCancel(context.Background())
+
+    tests := []struct {
+        name string
+        got  []int
+        want []int
+    }{
+        {"countSeq", takeSeq(countSeq(5), 100), []int{0, 1, 2, 3, 4}},
+        {"countSeq(0)", takeSeq(countSeq(0), 100), nil},
+        {"fibSeq", takeSeq(fibSeq(), 10), fibs},
+
+        // The iterators must give the same values as the channel generators.
+        {"countSeq == counter", takeSeq(countSeq(50), 50), collect(counter(ctx, 50))},
+        {"fibSeq == fibgen", takeSeq(fibSeq(), 1000), collect(fibgen(ctx))},
+
+        {"chanToSeq", takeSeq(chanToSeq(counter(ctx, 5)), 100), []int{0, 1, 2, 3, 4}},
+        {"genToSeq", takeSeq(genToSeq(fibgen), 10), fibs},
+
+        // seqToChan lets iterators be used in pipelines.
+        {"seqToChan",
+         collect(Take(ctx, Filter(ctx, seqToChan(ctx, fibSeq()), func(n int) bool { return n%2 == 0 }), 4)),
+         []int{2, 8, 34, 144}},
+    }
+    for _, tc := range tests {
+        if !slices.Equal(tc.got, tc.want) {
+            t.Errorf("%v: got %v, expected %v", tc.name, tc.got, tc.want)
+        }
+    }
+
+    //
+    // iter.Pull turns an iterator into a "next" function, which works like
+    // receiving from a generator's channel.
+    //
+    next, stop := iter.Pull(fibSeq())
+    var pulled []int
+    for i := 0; i < 10; i++ {
+        x, ok := next()
+        if !ok {
+            break
+        }
+        pulled = append(pulled, x)
+    }
+    stop()
+    if !slices.Equal(pulled, fibs) {
+        t.Errorf("iter.Pull: got %v, expected %v", pulled, fibs)
+    }
+
+    cancel()
+    checkGoroutinesEnd(t, before)
+}
+
+//
+// The benchmarks time the channel generators against the iterators. One
+// operation is generating one number, so ns/op is the time per value. Run
+// them like this:
+//
+//    $ go test -bench . *.go
+//
+// The values are added to benchSink. Since it's a global variable, the
+// compiler can't optimize the loops away.
+//
+var benchSink int
+
+func BenchmarkCounter(b *testing.B) {
+    ctx, cancel := context.WithCancel(context.Background())
+    defer cancel()
+    for n := range counter(ctx, b.N) {
+        benchSink += n
+    }
+}
+
+func BenchmarkCountSeq(b *testing.B) {
+    for n := range countSeq(b.N) {
+        benchSink += n
+    }
+}
+
+//
+// fibgen and fibSeq stop after 92 numbers, so these start them again as many
+// times as needed to get b.N numbers.
+//
+func BenchmarkFibgen(b *testing.B) {
+    ctx, cancel := context.WithCancel(context.Background())
+    defer cancel()
+    for i := 0; i < b.N; {
+        for n := range fibgen(ctx) {
+            benchSink += n
+            i++
+        }
+    }
+}
+
+func BenchmarkFibSeq(b *testing.B) {
+    for i := 0; i < b.N; {
+        for n := range fibSeq() {
+            benchSink += n
+            i++
+        }
+    }
+}
+
+func BenchmarkFibSeqPull(b *testing.B) {
+    for i := 0; i < b.N; {
+        next, stop := iter.Pull(fibSeq())
+        for n, ok := next(); ok; n, ok = next() {
+            benchSink += n
+            i++
+        }
+        stop()
+    }
+}
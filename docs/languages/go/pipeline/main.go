@@ -0,0 +1,57 @@
+// main.go
+
+//
+// A few examples of the generator libraries in this directory. go run can't
+// be given the _test.go files, so run it like this:
+//
+//    $ go run $(ls *.go | grep -v _test.go)
+//
+// The tests are in the _test.go files, along with benchmarks that time the
+// channel generators against the iterators. Run them like this:
+//
+//    $ go test *.go
+//    $ go test -bench . *.go
+//
+
+package main
+
+import (
+    "context"
+    "fmt"
+)
+
+func main() {
+    ctx, cancel := context.WithCancel(context.Background())
+    defer cancel()
+
+    //
+    // The sums of each batch of 3 even Fibonacci numbers.
+    //
+    evenFibs := Filter(ctx, fibgen(ctx), func(n int) bool { return n%2 == 0 })
+    sums := Map(ctx, Batch(ctx, Take(ctx, evenFibs, 9), 3), func(b []int) int {
+        return b[0] + b[1] + b[2]
+    })
+    fmt.Println("sums of batches of even Fibonacci numbers:", collect(sums))
+
+    fmt.Println("windows:", collect(Window(ctx, counter(ctx, 6), 3)))
+    fmt.Println("the first 20 primes:", collect(firstPrimes(ctx, 20)))
+
+    //
+    // Square the numbers with 4 workers, keeping them in order.
+    //
+    squares, wait := ParallelMapOrdered(ctx, counter(ctx, 10), 4,
+        func(ctx context.Context, n int) (int, error) { return n * n, nil })
+    fmt.Println("squares:", collect(squares), wait())
+
+    //
+    // Two subscribers to the same topic each get a copy of every message.
+    //
+    b := NewBroker[string]()
+    sub1, _ := b.Subscribe("news", 10, DropOldest)
+    sub2, _ := b.Subscribe("news", 10, DropOldest)
+    b.Publish(ctx, "news", "hello")
+    b.Publish(ctx, "news", "goodbye")
+    b.Close()
+    fmt.Println("subscriber 1 got", collect(sub1.C()))
+    fmt.Println("subscriber 2 got", collect(sub2.C()))
+}
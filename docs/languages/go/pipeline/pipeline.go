@@ -0,0 +1,298 @@
+// pipeline.go
+
+//
+// Generic pipeline stages for combining generators. A stage reads values
+// from one or more input channels, and sends its results on an output
+// channel, e.g.
+//
+//    ctx, cancel := context.WithCancel(context.Background())
+//    defer cancel()
+//    evens := Filter(ctx, counter(ctx, 100), func(n int) bool { return n%2 == 0 })
+//    squares := Map(ctx, evens, func(n int) int { return n * n })
+//    for sq := range Take(ctx, squares, 5) {
+//        fmt.Println(sq) // 0, 4, 16, 36, 64
+//    }
+//
+// Every stage follows the same rules:
+//
+// - it runs in its own goroutine, and closes its output channel when it
+//   finishes
+// - it finishes when its input is closed, or ctx is cancelled
+// - when it's blocked sending or receiving, it also waits on ctx.Done(), so
+//   cancelling ctx always lets it end
+//
+// Some stages, like Take, stop reading their input early. The stages
+// feeding them are then stuck until ctx is cancelled, so always cancel ctx
+// when you're done with a pipeline.
+//
+
+package main
+
+import (
+    "context"
+    "fmt"
+    "sync"
+)
+
+//
+// Sends x on ch, and returns true. If ctx is cancelled first, x is not sent
+// and it returns false.
+//
+func send[T any](ctx context.Context, ch chan<- T, x T) bool {
+    select {
+    case ch <- x:
+        return true
+    case <-ctx.Done():
+        return false
+    }
+}
+
+//
+// Receives the next value from ch. ok is false if ch is closed or ctx is
+// cancelled.
+//
+func recv[T any](ctx context.Context, ch <-chan T) (x T, ok bool) {
+    select {
+    case x, ok = <-ch:
+        return x, ok
+    case <-ctx.Done():
+        return x, false
+    }
+}
+
+//
+// Sends f(x) for every x in in.
+//
+func Map[T, U any](ctx context.Context, in <-chan T, f func(T) U) <-chan U {
+    out := make(chan U)
+    go func() {
+        defer close(out)
+        for {
+            x, ok := recv(ctx, in)
+            if !ok || !send(ctx, out, f(x)) {
+                return
+            }
+        }
+    }()
+    return out
+}
+
+//
+// Sends only the values x in in for which keep(x) is true.
+//
+func Filter[T any](ctx context.Context, in <-chan T, keep func(T) bool) <-chan T {
+    out := make(chan T)
+    go func() {
+        defer close(out)
+        for {
+            x, ok := recv(ctx, in)
+            if !ok {
+                return
+            }
+            if keep(x) && !send(ctx, out, x) {
+                return
+            }
+        }
+    }()
+    return out
+}
+
+//
+// Sends the first n values of in, and then closes its output. This is
+// useful for infinite generators like fibgen.
+//
+func Take[T any](ctx context.Context, in <-chan T, n int) <-chan T {
+    out := make(chan T)
+    go func() {
+        defer close(out)
+        for i := 0; i < n; i++ {
+            x, ok := recv(ctx, in)
+            if !ok || !send(ctx, out, x) {
+                return
+            }
+        }
+    }()
+    return out
+}
+
+//
+// Sends values from in as long as keep is true for them. The first value
+// for which keep is false is dropped, and the output is closed.
+//
+func TakeWhile[T any](ctx context.Context, in <-chan T, keep func(T) bool) <-chan T {
+    out := make(chan T)
+    go func() {
+        defer close(out)
+        for {
+            x, ok := recv(ctx, in)
+            if !ok || !keep(x) || !send(ctx, out, x) {
+                return
+            }
+        }
+    }()
+    return out
+}
+
+//
+// A pair of values, as sent by Zip.
+//
+type Pair[A, B any] struct {
+    First  A
+    Second B
+}
+
+func (p Pair[A, B]) String() string {
+    return fmt.Sprintf("(%v, %v)", p.First, p.Second)
+}
+
+//
+// Sends pairs made from the first values of a and b, then the second values
+// of a and b, and so on. It stops as soon as either a or b is closed.
+//
+func Zip[A, B any](ctx context.Context, a <-chan A, b <-chan B) <-chan Pair[A, B] {
+    out := make(chan Pair[A, B])
+    go func() {
+        defer close(out)
+        for {
+            x, ok := recv(ctx, a)
+            if !ok {
+                return
+            }
+            y, ok := recv(ctx, b)
+            if !ok || !send(ctx, out, Pair[A, B]{x, y}) {
+                return
+            }
+        }
+    }()
+    return out
+}
+
+//
+// Sends every value from all the ins, in whatever order they arrive (this is
+// called "fan-in"). The output is closed after all the ins are closed.
+//
+// Each input gets its own goroutine, and a sync.WaitGroup counts how many
+// are still running. The last one to finish closes the output.
+//
+func Merge[T any](ctx context.Context, ins ...<-chan T) <-chan T {
+    out := make(chan T)
+    var wg sync.WaitGroup
+    wg.Add(len(ins))
+    for _, in := range ins {
+        go func(in <-chan T) {
+            defer wg.Done()
+            for {
+                x, ok := recv(ctx, in)
+                if !ok || !send(ctx, out, x) {
+                    return
+                }
+            }
+        }(in)
+    }
+    go func() {
+        wg.Wait()
+        close(out)
+    }()
+    return out
+}
+
+//
+// Returns n channels that each get a copy of every value from in (this is
+// called "fan-out"). Each value is sent to the first output, then the
+// second, and so on, and the next value isn't read from in until all n
+// outputs have it. So the outputs must be read at the same time (e.g. in
+// different goroutines), and if one stops being read the others get stuck
+// waiting for it.
+//
+func Tee[T any](ctx context.Context, in <-chan T, n int) []<-chan T {
+    outs := make([]chan T, n)
+    result := make([]<-chan T, n)
+    for i := range outs {
+        outs[i] = make(chan T)
+        result[i] = outs[i]
+    }
+    go func() {
+        defer func() {
+            for _, out := range outs {
+                close(out)
+            }
+        }()
+        for {
+            x, ok := recv(ctx, in)
+            if !ok {
+                return
+            }
+            for _, out := range outs {
+                if !send(ctx, out, x) {
+                    return
+                }
+            }
+        }
+    }()
+    return result
+}
+
+//
+// Groups the values from in into slices of length size, e.g. with size 3
+// the values 1, 2, 3, 4, 5 are sent as [1 2 3] and [4 5]. The last batch is
+// shorter if the number of values isn't a multiple of size. A size less than
+// 1 is treated as 1; otherwise no batch would ever be full, and Batch would
+// keep every value of an endless generator.
+//
+func Batch[T any](ctx context.Context, in <-chan T, size int) <-chan []T {
+    size = max(size, 1)
+    out := make(chan []T)
+    go func() {
+        defer close(out)
+        var batch []T
+        for {
+            x, ok := recv(ctx, in)
+            if !ok {
+                break
+            }
+            batch = append(batch, x)
+            if len(batch) == size {
+                if !send(ctx, out, batch) {
+                    return
+                }
+                batch = nil
+            }
+        }
+        if len(batch) > 0 && ctx.Err() == nil {
+            send(ctx, out, batch)
+        }
+    }()
+    return out
+}
+
+//
+// Sends every run of size consecutive values from in, i.e. a "sliding
+// window", e.g. with size 3 the values 1, 2, 3, 4, 5 are sent as [1 2 3],
+// [2 3 4] and [3 4 5]. If in has fewer than size values, nothing is sent.
+// A size less than 1 is treated as 1, rather than sending an empty window
+// for every value.
+//
+// Each window is a new slice, so the receiver can keep it.
+//
+func Window[T any](ctx context.Context, in <-chan T, size int) <-chan []T {
+    size = max(size, 1)
+    out := make(chan []T)
+    go func() {
+        defer close(out)
+        var window []T
+        for {
+            x, ok := recv(ctx, in)
+            if !ok {
+                return
+            }
+            window = append(window, x)
+            if len(window) > size {
+                window = window[1:]
+            }
+            if len(window) == size && !send(ctx, out, append([]T{}, window...)) {
+                return
+            }
+        }
+    }()
+    return out
+}
@@ -0,0 +1,151 @@
+// pipeline_test.go
+
+//
+// Tests for the pipeline stages. Run all the tests in this directory like
+// this:
+//
+//    $ go test *.go
+//
+// Add -race to check for data races.
+//
+
+package main
+
+import (
+    "context"
+    "fmt"
+    "runtime"
+    "slices"
+    "sync"
+    "testing"
+    "time"
+)
+
+//
+// Fails the test unless the number of running goroutines drops back to
+// before within a second. Goroutines don't end the instant their context is
+// cancelled, so it's not enough to check just once.
+//
+func checkGoroutinesEnd(t *testing.T, before int) {
+    t.Helper()
+    for start := time.Now(); time.Since(start) < time.Second; {
+        if runtime.NumGoroutine() <= before {
+            return
+        }
+        time.Sleep(10 * time.Millisecond)
+    }
+    t.Errorf("%v goroutines running, expected %v", runtime.NumGoroutine(), before)
+}
+
+func TestPipeline(t *testing.T) {
+    before := runtime.NumGoroutine()
+    ctx, cancel := context.WithCancel(context.Background())
+
+    isEven := func(n int) bool { return n%2 == 0 }
+    square := func(n int) int { return n * n }
+
+    intTests := []struct {
+        name string
+        ch   <-chan int
+        want []int
+    }{
+        {"Map", Map(ctx, counter(ctx, 5), square), []int{0, 1, 4, 9, 16}},
+        {"Filter", Filter(ctx, counter(ctx, 10), isEven), []int{0, 2, 4, 6, 8}},
+        {"Take fibgen", Take(ctx, fibgen(ctx), 10), []int{1, 1, 2, 3, 5, 8, 13, 21, 34, 55}},
+        {"Take more than there are", Take(ctx, counter(ctx, 3), 10), []int{0, 1, 2}},
+        {"Take 0", Take(ctx, counter(ctx, 3), 0), nil},
+        {"TakeWhile", TakeWhile(ctx, fibgen(ctx), func(n int) bool { return n < 100 }),
+         []int{1, 1, 2, 3, 5, 8, 13, 21, 34, 55, 89}},
+    }
+    for _, tc := range intTests {
+        if got := collect(tc.ch); !slices.Equal(got, tc.want) {
+            t.Errorf("%v: got %v, expected %v", tc.name, got, tc.want)
+        }
+    }
+
+    //
+    // Stages with other types of output are compared as strings.
+    //
+    stringTests := []struct {
+        name string
+        got  any
+        want string
+    }{
+        {"Map to string",
+         collect(Map(ctx, counter(ctx, 3), func(n int) string { return fmt.Sprint("#", n) })),
+         "[#0 #1 #2]"},
+        {"Zip", collect(Zip(ctx, counter(ctx, 5), fibgen(ctx))),
+         "[(0, 1) (1, 1) (2, 2) (3, 3) (4, 5)]"},
+        {"Batch", collect(Batch(ctx, counter(ctx, 7), 3)), "[[0 1 2] [3 4 5] [6]]"},
+        {"Batch exact", collect(Batch(ctx, counter(ctx, 6), 3)), "[[0 1 2] [3 4 5]]"},
+        {"Window", collect(Window(ctx, counter(ctx, 5), 3)), "[[0 1 2] [1 2 3] [2 3 4]]"},
+        {"Window too short", collect(Window(ctx, counter(ctx, 2), 3)), "[]"},
+        {"Batch size 0", collect(Batch(ctx, counter(ctx, 3), 0)), "[[0] [1] [2]]"},
+        {"Batch size -1", collect(Batch(ctx, Take(ctx, fibgen(ctx), 3), -1)), "[[1] [1] [2]]"},
+        {"Window size 0", collect(Window(ctx, counter(ctx, 3), 0)), "[[0] [1] [2]]"},
+    }
+    for _, tc := range stringTests {
+        if got := fmt.Sprint(tc.got); got != tc.want {
+            t.Errorf("%v: got %v, expected %v", tc.name, got, tc.want)
+        }
+    }
+
+    //
+    // Merge sends the values in an unpredictable order, so count them
+    // instead.
+    //
+    merged := collect(Merge(ctx, counter(ctx, 100), counter(ctx, 50), Take(ctx, fibgen(ctx), 10)))
+    sum := 0
+    for _, n := range merged {
+        sum += n
+    }
+    if len(merged) != 160 || sum != 4950+1225+143 {
+        t.Errorf("Merge: got %v values adding up to %v", len(merged), sum)
+    }
+    if got := collect(Merge[int](ctx)); len(got) != 0 {
+        t.Errorf("Merge of nothing: got %v", got)
+    }
+
+    //
+    // Each output of Tee must be read at the same time, so read them in
+    // their own goroutines.
+    //
+    outs := Tee(ctx, counter(ctx, 5), 3)
+    results := make([][]int, len(outs))
+    var wg sync.WaitGroup
+    for i, out := range outs {
+        wg.Add(1)
+        go func(i int, out <-chan int) {
+            defer wg.Done()
+            results[i] = collect(out)
+        }(i, out)
+    }
+    wg.Wait()
+    for i := range results {
+        if !slices.Equal(results[i], []int{0, 1, 2, 3, 4}) {
+            t.Errorf("Tee output %v: got %v", i, results[i])
+        }
+    }
+
+    //
+    // A longer pipeline: the sums of each batch of 3 even Fibonacci numbers.
+    //
+    evenFibs := Filter(ctx, fibgen(ctx), isEven)
+    sums := Map(ctx, Batch(ctx, Take(ctx, evenFibs, 6), 3), func(b []int) int {
+        return b[0] + b[1] + b[2]
+    })
+    if got, want := collect(sums), []int{2 + 8 + 34, 144 + 610 + 2584}; !slices.Equal(got, want) {
+        t.Errorf("pipeline: got %v, expected %v", got, want)
+    }
+
+    //
+    // Cancelling ctx must end every stage, including ones still waiting on
+    // infinite generators, and ones nobody is reading from.
+    //
+    Map(ctx, fibgen(ctx), square)
+    Merge(ctx, fibgen(ctx), fibgen(ctx))
+    Tee(ctx, fibgen(ctx), 2)
+    Window(ctx, fibgen(ctx), 2)
+    cancel()
+    checkGoroutinesEnd(t, before)
+}
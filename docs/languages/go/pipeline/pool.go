@@ -0,0 +1,184 @@
+// pool.go
+
+//
+// A worker pool: a fixed number of goroutines ("workers") that read values
+// from a generator's channel and apply a function to each of them at the
+// same time. This is useful when the function is slow, e.g. it downloads a
+// web page or does a big calculation.
+//
+//    results, wait := ParallelMap(ctx, counter(ctx, 100), 8, slowSquare)
+//    for r := range results {
+//        fmt.Println(r)
+//    }
+//    if err := wait(); err != nil {
+//        fmt.Println("error:", err)
+//    }
+//
+// ParallelMap sends the results as soon as they are ready, so they may be
+// in a different order than the input. ParallelMapOrdered sends them in the
+// same order as the input.
+//
+// If the function returns an error, the pool cancels the context it passes
+// to the other calls, stops reading input, and closes the results channel.
+// wait then returns the first error.
+//
+
+package main
+
+import (
+    "context"
+    "sync"
+)
+
+//
+// Applies f to every value from in using workers goroutines, and sends the
+// results in the order they finish. Read all the results, and then call
+// wait to get the first error (or nil).
+//
+func ParallelMap[T, U any](ctx context.Context, in <-chan T, workers int,
+                           f func(context.Context, T) (U, error)) (<-chan U, func() error) {
+    return runPool(ctx, in, workers, false, f)
+}
+
+//
+// Like ParallelMap, but the results are sent in the same order as the values
+// they came from.
+//
+func ParallelMapOrdered[T, U any](ctx context.Context, in <-chan T, workers int,
+                                  f func(context.Context, T) (U, error)) (<-chan U, func() error) {
+    return runPool(ctx, in, workers, true, f)
+}
+
+//
+// A value from the input and its position, and the result of applying f to
+// it.
+//
+type job[T any] struct {
+    index int
+    value T
+}
+
+type poolResult[U any] struct {
+    index int
+    value U
+}
+
+//
+// The pool has three parts, connected by channels:
+//
+//    in --> dispatcher --jobs--> workers --results--> emitter --> out
+//
+// The dispatcher numbers each value, the workers call f, and the emitter
+// sends the results to out (putting them back in order if ordered is true).
+//
+// In ordered mode, a result that finishes early has to be held until all
+// the results before it are sent. To stop the number of held results from
+// growing without limit, the dispatcher must take a token from the tokens
+// channel before handing out a job, and the emitter puts a token back after
+// sending a result. So at most cap(tokens) values are in the pool at once.
+//
+func runPool[T, U any](parent context.Context, in <-chan T, workers int, ordered bool,
+                       f func(context.Context, T) (U, error)) (<-chan U, func() error) {
+    if workers < 1 {
+        workers = 1
+    }
+    ctx, cancel := context.WithCancel(parent)
+
+    //
+    // The first error cancels ctx, which makes every part of the pool stop.
+    // sync.Once makes sure only the first error is saved.
+    //
+    var once sync.Once
+    var firstErr error
+    fail := func(err error) {
+        once.Do(func() {
+            firstErr = err
+            cancel()
+        })
+    }
+
+    tokens := make(chan struct{}, 2*workers)
+    jobs := make(chan job[T])
+    results := make(chan poolResult[U])
+    out := make(chan U)
+
+    // dispatcher
+    go func() {
+        defer close(jobs)
+        for i := 0; ; i++ {
+            if !send(ctx, tokens, struct{}{}) {
+                return
+            }
+            x, ok := recv(ctx, in)
+            if !ok || !send(ctx, jobs, job[T]{i, x}) {
+                return
+            }
+        }
+    }()
+
+    // workers
+    var wg sync.WaitGroup
+    wg.Add(workers)
+    for w := 0; w < workers; w++ {
+        go func() {
+            defer wg.Done()
+            for j := range jobs {
+                y, err := f(ctx, j.value)
+                if err != nil {
+                    fail(err)
+                    return
+                }
+                if !send(ctx, results, poolResult[U]{j.index, y}) {
+                    return
+                }
+            }
+        }()
+    }
+    go func() {
+        wg.Wait()
+        close(results)
+    }()
+
+    // emitter
+    emitterDone := make(chan struct{})
+    go func() {
+        defer close(emitterDone)
+        defer close(out)
+        emit := func(y U) bool {
+            if !send(ctx, out, y) {
+                return false
+            }
+            <-tokens
+            return true
+        }
+        pending := map[int]U{} // finished results waiting for earlier ones
+        next := 0              // index of the next result to send
+        for r := range results {
+            if !ordered {
+                if !emit(r.value) {
+                    return
+                }
+                continue
+            }
+            pending[r.index] = r.value
+            for y, ok := pending[next]; ok; y, ok = pending[next] {
+                delete(pending, next)
+                if !emit(y) {
+                    return
+                }
+                next++
+            }
+        }
+    }()
+
+    wait := func() error {
+        <-emitterDone
+        wg.Wait()
+        cancel()
+        if firstErr != nil {
+            return firstErr
+        }
+        return parent.Err()
+    }
+    return out, wait
+}
@@ -0,0 +1,120 @@
+// pool_test.go
+
+//
+// Tests for the worker pool.
+//
+
+package main
+
+import (
+    "context"
+    "errors"
+    "runtime"
+    "sync/atomic"
+    "testing"
+    "time"
+)
+
+//
+// Waits a bit (longer for smaller n, so later values tend to finish first),
+// and then returns n squared.
+//
+func slowSquare(ctx context.Context, n int) (int, error) {
+    select {
+    case <-time.After(time.Duration(20-n%20) * time.Millisecond):
+    case <-ctx.Done():
+        return 0, ctx.Err()
+    }
+    return n * n, nil
+}
+
+func TestPool(t *testing.T) {
+    before := runtime.NumGoroutine()
+    ctx, cancel := context.WithCancel(context.Background())
+    defer cancel()
+
+    //
+    // Unordered: every result arrives, but in any order.
+    //
+    results, wait := ParallelMap(ctx, counter(ctx, 40), 8, slowSquare)
+    got := collect(results)
+    sum := 0
+    for _, x := range got {
+        sum += x
+    }
+    if err := wait(); len(got) != 40 || sum != 20540 || err != nil {
+        t.Errorf("ParallelMap: got %v results adding up to %v, error %v", len(got), sum, err)
+    }
+
+    //
+    // Ordered: the results come out in input order, even though the later
+    // values finish first.
+    //
+    results, wait = ParallelMapOrdered(ctx, counter(ctx, 40), 8, slowSquare)
+    got = collect(results)
+    if err := wait(); err != nil {
+        t.Errorf("ParallelMapOrdered: error %v", err)
+    }
+    inOrder := len(got) == 40
+    for i := range got {
+        inOrder = inOrder && got[i] == i*i
+    }
+    if !inOrder {
+        t.Errorf("ParallelMapOrdered: got %v", got)
+    }
+
+    //
+    // 8 workers doing 16 jobs of 20ms each should take about 40ms, not
+    // 320ms.
+    //
+    start := time.Now()
+    sleep := func(ctx context.Context, n int) (int, error) {
+        time.Sleep(20 * time.Millisecond)
+        return n, nil
+    }
+    results, wait = ParallelMapOrdered(ctx, counter(ctx, 16), 8, sleep)
+    collect(results)
+    elapsed := time.Since(start)
+    if err := wait(); err != nil || elapsed >= 200*time.Millisecond {
+        t.Errorf("16 jobs of 20ms on 8 workers took %v, error %v", elapsed, err)
+    }
+
+    //
+    // An error stops the pool early. Count how many times f is called, to
+    // make sure the rest of the input is not processed.
+    //
+    errBad := errors.New("13 is unlucky")
+    var calls atomic.Int64
+    unlucky := func(ctx context.Context, n int) (int, error) {
+        calls.Add(1)
+        if n == 13 {
+            return 0, errBad
+        }
+        return slowSquare(ctx, n)
+    }
+    for _, ordered := range []bool{false, true} {
+        calls.Store(0)
+        results, wait = runPool(ctx, counter(ctx, 1000), 4, ordered, unlucky)
+        got = collect(results)
+        if err := wait(); err != errBad || len(got) >= 1000 || calls.Load() >= 100 {
+            t.Errorf("ordered=%v: error %v after %v results and %v calls, expected it to stop early",
+                     ordered, err, len(got), calls.Load())
+        }
+    }
+
+    //
+    // Cancelling the caller's context stops the pool too, and wait reports
+    // it.
+    //
+    ctx2, cancel2 := context.WithCancel(ctx)
+    results, wait = ParallelMap(ctx2, fibgen(ctx2), 4, slowSquare)
+    <-results
+    cancel2()
+    collect(results)
+    if err := wait(); err != context.Canceled {
+        t.Errorf("cancelled pool: error %v, expected %v", err, context.Canceled)
+    }
+
+    cancel()
+    checkGoroutinesEnd(t, before)
+}
@@ -0,0 +1,121 @@
+// sieve.go
+
+//
+// The concurrent prime sieve, from Doug McIlroy's and Tony Hoare's work on
+// communicating processes, and made famous by Rob Pike's Go talks.
+//
+// A generator sends 2, 3, 4, 5, ... The first number it sends, 2, is prime,
+// so a filter goroutine is added that removes all the multiples of 2. The
+// first number to get through that filter, 3, is also prime, so another
+// filter is added after it that removes the multiples of 3, and so on:
+//
+//    2, 3, 4, ... --> filter 2 --> filter 3 --> filter 5 --> ... --> primes
+//
+// Every number that gets through all the filters is prime, and gets a filter
+// of its own. So finding n primes takes n+1 goroutines, all running at the
+// same time, and each number is passed down the chain until a filter
+// removes it.
+//
+// This is a nice demonstration of goroutines and channels, but it's much
+// slower than a sequential sieve, or even testing each number with is_prime:
+// most of the time is spent passing numbers from one goroutine to the next.
+//
+
+package main
+
+import "context"
+
+//
+// Returns a channel that generates 2, 3, 4, ..., limit. If limit is
+// negative there's no limit.
+//
+func naturalsFrom2(ctx context.Context, limit int) <-chan int {
+    ch := make(chan int)
+    go func() {
+        defer close(ch)
+        for i := 2; limit < 0 || i <= limit; i++ {
+            if !send(ctx, ch, i) {
+                return
+            }
+        }
+    }()
+    return ch
+}
+
+//
+// Sends the values from in that aren't multiples of p.
+//
+func sieveFilter(ctx context.Context, in <-chan int, p int) <-chan int {
+    return Filter(ctx, in, func(n int) bool { return n%p != 0 })
+}
+
+//
+// Returns a channel that generates primes in order using the concurrent
+// sieve. It stops after count primes, or when the next prime would be
+// bigger than limit; a negative count or limit means no limit.
+//
+// The sieve has its own context, derived from ctx. When it stops, it
+// cancels that context, which ends every filter goroutine in the chain. So
+// a bounded sieve cleans up after itself without the caller cancelling ctx.
+//
+func sieve(ctx context.Context, count, limit int) <-chan int {
+    out := make(chan int)
+    go func() {
+        ctx, cancel := context.WithCancel(ctx)
+        defer cancel()
+        defer close(out)
+        ch := naturalsFrom2(ctx, limit)
+        for i := 0; count < 0 || i < count; i++ {
+            p, ok := recv(ctx, ch)
+            if !ok || !send(ctx, out, p) {
+                return
+            }
+            ch = sieveFilter(ctx, ch, p)
+        }
+    }()
+    return out
+}
+
+//
+// Returns a channel that generates all the primes, until ctx is cancelled.
+//
+func primeSieve(ctx context.Context) <-chan int {
+    return sieve(ctx, -1, -1)
+}
+
+//
+// Returns a channel that generates the first n primes.
+//
+func firstPrimes(ctx context.Context, n int) <-chan int {
+    return sieve(ctx, max(n, 0), -1)
+}
+
+//
+// Returns a channel that generates the primes less than or equal to n.
+//
+func primesUpTo(ctx context.Context, n int) <-chan int {
+    return sieve(ctx, -1, max(n, 0))
+}
+
+//
+// is_prime from ../primes.go, to check the sieve against.
+//
+// Returns true if the integer n is prime, and false otherwise.
+func is_prime(n int) bool {
+    if n < 2 {
+        return false
+    } else if n == 2 {
+        return true
+    } else if n % 2 == 0 {
+        return false
+    } else {
+        candidate := 3
+        for candidate * candidate <= n {
+            if n % candidate == 0 {
+                return false
+            }
+            candidate += 2
+        }
+        return true
+    }
+}
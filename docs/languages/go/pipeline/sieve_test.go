@@ -0,0 +1,90 @@
+// sieve_test.go
+
+//
+// Tests for the prime sieve.
+//
+
+package main
+
+import (
+    "context"
+    "runtime"
+    "slices"
+    "testing"
+)
+
+//
+// Returns the primes up to n, found sequentially with is_prime.
+//
+func sequentialPrimes(n int) []int {
+    var result []int
+    for i := 0; i <= n; i++ {
+        if is_prime(i) {
+            result = append(result, i)
+        }
+    }
+    return result
+}
+
+func TestSieve(t *testing.T) {
+    before := runtime.NumGoroutine()
+    ctx, cancel := context.WithCancel(context.Background())
+    defer cancel()
+
+    first10 := []int{2, 3, 5, 7, 11, 13, 17, 19, 23, 29}
+    tests := []struct {
+        name string
+        ch   <-chan int
+        want []int
+    }{
+        {"firstPrimes(10)", firstPrimes(ctx, 10), first10},
+        {"firstPrimes(0)", firstPrimes(ctx, 0), nil},
+        {"primesUpTo(29)", primesUpTo(ctx, 29), first10},
+        {"primesUpTo(28)", primesUpTo(ctx, 28), first10[:9]},
+        {"primesUpTo(1)", primesUpTo(ctx, 1), nil},
+        {"primesUpTo(2)", primesUpTo(ctx, 2), []int{2}},
+    }
+    for _, tc := range tests {
+        if got := collect(tc.ch); !slices.Equal(got, tc.want) {
+            t.Errorf("%v = %v, expected %v", tc.name, got, tc.want)
+        }
+    }
+
+    //
+    // The sieve must agree with is_prime. There are 1229 primes less than
+    // 10000, as primes.go says.
+    //
+    got := collect(primesUpTo(ctx, 10000))
+    if len(got) != 1229 || !slices.Equal(got, sequentialPrimes(10000)) {
+        t.Errorf("primesUpTo(10000) gave %v primes that don't match is_prime", len(got))
+    }
+    got = collect(firstPrimes(ctx, 500))
+    if len(got) != 500 || !slices.Equal(got, sequentialPrimes(got[len(got)-1])) {
+        t.Errorf("firstPrimes(500) gave %v primes that don't match is_prime", len(got))
+    }
+
+    //
+    // A bounded sieve tears down its own chain of filters when it's done,
+    // even though ctx hasn't been cancelled.
+    //
+    checkGoroutinesEnd(t, before)
+
+    //
+    // An unbounded sieve has a goroutine per prime found so far, and
+    // cancelling ctx ends all of them.
+    //
+    primes := primeSieve(ctx)
+    for i := 0; i < 100; i++ {
+        <-primes
+    }
+    if n := runtime.NumGoroutine() - before; n <= 100 {
+        t.Errorf("%v goroutines after 100 primes, expected one per prime", n)
+    }
+    got = collect(TakeWhile(ctx, primeSieve(ctx), func(p int) bool { return p < 100 }))
+    if want := sequentialPrimes(100); !slices.Equal(got, want) {
+        t.Errorf("primeSieve with TakeWhile = %v, expected %v", got, want)
+    }
+
+    cancel()
+    checkGoroutinesEnd(t, before)
+}
@@ -0,0 +1,270 @@
+// throttle.go
+
+//
+// Pipeline stages that deal with time:
+//
+// - Throttle lets at most N values per second through (a token bucket)
+// - Debounce only passes on a value once the input has been quiet for a
+//   while, e.g. to wait until someone has stopped typing
+// - Timeout stops with an error if the next value takes too long to arrive
+// - Heartbeat sends a "still alive" tick whenever the input is quiet
+//
+// Code that depends on the time is hard to test, since the tests would have
+// to really wait, and the timing would vary from run to run. So these stages
+// get the time from a Clock. The real program uses realClock, and the tests
+// (in throttle_test.go) use a fakeClock whose time only moves when the test
+// calls Advance.
+//
+
+package main
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "time"
+)
+
+//
+// A source of time. NewTimer returns a Timer whose channel gets the time
+// once d has passed.
+//
+type Clock interface {
+    Now() time.Time
+    NewTimer(d time.Duration) Timer
+}
+
+type Timer interface {
+    C() <-chan time.Time
+    Stop()
+}
+
+//
+// realClock uses the computer's clock via the standard time package.
+//
+type realClock struct{}
+
+type realTimer struct {
+    t *time.Timer
+}
+
+func (realClock) Now() time.Time {
+    return time.Now()
+}
+
+func (realClock) NewTimer(d time.Duration) Timer {
+    return realTimer{time.NewTimer(d)}
+}
+
+func (t realTimer) C() <-chan time.Time {
+    return t.t.C
+}
+
+func (t realTimer) Stop() {
+    t.t.Stop()
+}
+
+//
+// Lets the values from in through at no more than perSecond per second.
+//
+// This is a "token bucket": the bucket holds up to burst tokens, a new token
+// is added every 1/perSecond seconds, and each value must take a token
+// before it's sent. If the bucket is empty the value waits for the next
+// token. So if the input has been slow for a while, up to burst values can
+// then go through at once.
+//
+// Instead of adding tokens one at a time, it keeps track of full, the time
+// at which the bucket will be full again. At time now, the bucket is missing
+// (full - now) / interval tokens.
+//
+func Throttle[T any](ctx context.Context, clock Clock, in <-chan T, perSecond, burst int) <-chan T {
+    interval := time.Second / time.Duration(max(perSecond, 1))
+    burst = max(burst, 1)
+    out := make(chan T)
+    go func() {
+        defer close(out)
+        full := clock.Now()
+        for {
+            x, ok := recv(ctx, in)
+            if !ok {
+                return
+            }
+
+            //
+            // There's at least one token in the bucket when it's missing at
+            // most burst-1 tokens, i.e. when full - now <= (burst-1)*interval.
+            //
+            now := clock.Now()
+            ready := full.Add(-time.Duration(burst-1) * interval)
+            if now.Before(ready) {
+                if !sleep(ctx, clock, ready.Sub(now)) {
+                    return
+                }
+                now = clock.Now()
+            }
+            if full.Before(now) {
+                full = now
+            }
+            full = full.Add(interval) // take a token
+
+            if !send(ctx, out, x) {
+                return
+            }
+        }
+    }()
+    return out
+}
+
+//
+// Waits for d to pass on clock. Returns false if ctx is cancelled first.
+//
+func sleep(ctx context.Context, clock Clock, d time.Duration) bool {
+    timer := clock.NewTimer(d)
+    defer timer.Stop()
+    select {
+    case <-timer.C():
+        return true
+    case <-ctx.Done():
+        return false
+    }
+}
+
+//
+// Sends a value from in only after no new value has arrived for quiet. If
+// several values arrive close together, only the last one is sent. When in
+// is closed, the last value (if it hasn't been sent yet) is sent right away.
+//
+func Debounce[T any](ctx context.Context, clock Clock, in <-chan T, quiet time.Duration) <-chan T {
+    out := make(chan T)
+    go func() {
+        defer close(out)
+        var latest T
+        var timer Timer
+        var timerC <-chan time.Time // nil when no value is waiting
+        defer func() {
+            if timer != nil {
+                timer.Stop()
+            }
+        }()
+        for {
+            //
+            // Receiving from a nil channel blocks forever, so the timer case
+            // can only happen when timerC has been set.
+            //
+            select {
+            case x, ok := <-in:
+                if !ok {
+                    if timerC != nil {
+                        send(ctx, out, latest)
+                    }
+                    return
+                }
+                latest = x
+                if timer != nil {
+                    timer.Stop()
+                }
+                timer = clock.NewTimer(quiet)
+                timerC = timer.C()
+            case <-timerC:
+                timer, timerC = nil, nil
+                if !send(ctx, out, latest) {
+                    return
+                }
+            case <-ctx.Done():
+                return
+            }
+        }
+    }()
+    return out
+}
+
+var ErrTimeout = errors.New("pipeline: timed out waiting for a value")
+
+//
+// Sends the values from in, but stops if any value takes longer than limit
+// to arrive (timed from when the previous value was sent). Read all the
+// values, and then call wait to see why it stopped: wait returns ErrTimeout
+// if it timed out, ctx.Err() if ctx was cancelled (whether it was waiting
+// for a value or sending one), and nil if in was closed normally.
+//
+func Timeout[T any](ctx context.Context, clock Clock, in <-chan T, limit time.Duration) (<-chan T, func() error) {
+    out := make(chan T)
+    done := make(chan struct{})
+    var err error
+    go func() {
+        defer close(done)
+        defer close(out)
+        for {
+            timer := clock.NewTimer(limit)
+            select {
+            case x, ok := <-in:
+                timer.Stop()
+                if !ok {
+                    return
+                }
+                if !send(ctx, out, x) {
+                    err = ctx.Err()
+                    return
+                }
+            case <-timer.C():
+                err = ErrTimeout
+                return
+            case <-ctx.Done():
+                timer.Stop()
+                err = ctx.Err()
+                return
+            }
+        }
+    }()
+    wait := func() error {
+        <-done
+        return err
+    }
+    return out, wait
+}
+
+//
+// A value sent by Heartbeat: either a value from the input, or a heartbeat
+// tick (when Heartbeat is true).
+//
+type Beat[T any] struct {
+    Value     T
+    Heartbeat bool
+}
+
+func (b Beat[T]) String() string {
+    if b.Heartbeat {
+        return "<heartbeat>"
+    }
+    return fmt.Sprint(b.Value)
+}
+
+//
+// Passes on the values from in, and also sends a heartbeat tick every time
+// interval passes without a value. This lets the receiver tell the
+// difference between "the input is slow" and "something is stuck".
+//
+func Heartbeat[T any](ctx context.Context, clock Clock, in <-chan T, interval time.Duration) <-chan Beat[T] {
+    out := make(chan Beat[T])
+    go func() {
+        defer close(out)
+        for {
+            timer := clock.NewTimer(interval)
+            select {
+            case x, ok := <-in:
+                timer.Stop()
+                if !ok || !send(ctx, out, Beat[T]{Value: x}) {
+                    return
+                }
+            case <-timer.C():
+                if !send(ctx, out, Beat[T]{Heartbeat: true}) {
+                    return
+                }
+            case <-ctx.Done():
+                timer.Stop()
+                return
+            }
+        }
+    }()
+    return out
+}
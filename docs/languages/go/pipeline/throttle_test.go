@@ -0,0 +1,372 @@
+// throttle_test.go
+
+//
+// Tests for the time-based stages, using a fake clock.
+//
+
+package main
+
+import (
+    "context"
+    "runtime"
+    "sync"
+    "testing"
+    "time"
+)
+
+//
+// A Clock for testing. Its time only changes when Advance is called, and
+// then any timers that are due fire.
+//
+type fakeClock struct {
+    mu      sync.Mutex
+    now     time.Time
+    timers  []*fakeTimer // timers that haven't fired or been stopped
+    created int          // number of timers ever created
+}
+
+type fakeTimer struct {
+    clock *fakeClock
+    at    time.Time
+    ch    chan time.Time
+}
+
+func newFakeClock() *fakeClock {
+    return &fakeClock{now: time.Date(2022, time.May, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func (c *fakeClock) Now() time.Time {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    return c.now
+}
+
+func (c *fakeClock) NewTimer(d time.Duration) Timer {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    // The channel has room for one value, so firing never blocks.
+    t := &fakeTimer{c, c.now.Add(d), make(chan time.Time, 1)}
+    c.created++
+    if d <= 0 {
+        t.ch <- c.now
+    } else {
+        c.timers = append(c.timers, t)
+    }
+    return t
+}
+
+func (t *fakeTimer) C() <-chan time.Time {
+    return t.ch
+}
+
+func (t *fakeTimer) Stop() {
+    c := t.clock
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    for i, other := range c.timers {
+        if other == t {
+            c.timers = append(c.timers[:i], c.timers[i+1:]...)
+            return
+        }
+    }
+}
+
+//
+// Moves the clock forward by d, and fires every timer that is now due.
+//
+func (c *fakeClock) Advance(d time.Duration) {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    c.now = c.now.Add(d)
+    var waiting []*fakeTimer
+    for _, t := range c.timers {
+        if t.at.After(c.now) {
+            waiting = append(waiting, t)
+        } else {
+            t.ch <- c.now
+        }
+    }
+    c.timers = waiting
+}
+
+//
+// Waits (in real time) until at least n timers have been created. Tests call
+// this to make sure a stage has got to the point where it's waiting on a
+// timer before they call Advance. Returns false if it takes over a second.
+//
+func (c *fakeClock) waitTimers(n int) bool {
+    for start := time.Now(); time.Since(start) < time.Second; {
+        if c.timersCreated() >= n {
+            return true
+        }
+        time.Sleep(time.Millisecond)
+    }
+    return false
+}
+
+//
+// Returns the number of timers created so far.
+//
+func (c *fakeClock) timersCreated() int {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    return c.created
+}
+
+//
+// Returns true if a value is ready on ch right now.
+//
+func ready(ch <-chan int) bool {
+    // Give the stage's goroutine a chance to run first.
+    time.Sleep(5 * time.Millisecond)
+    select {
+    case <-ch:
+        return true
+    default:
+        return false
+    }
+}
+
+//
+// Throttle at 10 per second, with a burst of 2: values 0 and 1 go straight
+// through, and then one more every 100ms.
+//
+func TestThrottle(t *testing.T) {
+    before := runtime.NumGoroutine()
+    ctx, cancel := context.WithCancel(context.Background())
+    defer func() {
+        cancel()
+        checkGoroutinesEnd(t, before)
+    }()
+
+    clock := newFakeClock()
+    start := clock.Now()
+    throttled := Throttle(ctx, clock, counter(ctx, 5), 10, 2)
+    if x, y := <-throttled, <-throttled; x != 0 || y != 1 {
+        t.Errorf("burst: got %v, %v, expected 0, 1", x, y)
+    }
+    for i := 2; i < 5; i++ {
+        if !clock.waitTimers(i - 1) {
+            t.Fatalf("throttle didn't wait for a timer before %v", i)
+        }
+        clock.Advance(99 * time.Millisecond)
+        if ready(throttled) {
+            t.Fatalf("%v arrived early", i)
+        }
+        clock.Advance(1 * time.Millisecond)
+        x := <-throttled
+        if elapsed := clock.Now().Sub(start); x != i || elapsed != time.Duration(i-1)*100*time.Millisecond {
+            t.Errorf("got %v after %v, expected %v after %v", x, elapsed, i, time.Duration(i-1)*100*time.Millisecond)
+        }
+    }
+    if _, ok := <-throttled; ok {
+        t.Errorf("throttle didn't close")
+    }
+
+    //
+    // After a quiet spell the bucket refills, but only up to burst tokens.
+    //
+    in := make(chan int)
+    throttled = Throttle(ctx, clock, in, 10, 2)
+    clock.Advance(10 * time.Second)
+    timers := clock.timersCreated()
+    results := make(chan int)
+    go func() {
+        for i := 0; i < 3; i++ {
+            in <- i
+            results <- <-throttled
+        }
+    }()
+    if x, y := <-results, <-results; x != 0 || y != 1 {
+        t.Errorf("after refill: got %v, %v, expected 0, 1", x, y)
+    }
+    if !clock.waitTimers(timers + 1) {
+        t.Fatalf("throttle didn't wait once the burst was used up")
+    }
+    clock.Advance(100 * time.Millisecond)
+    if x := <-results; x != 2 {
+        t.Errorf("after refill: got %v, expected 2", x)
+    }
+}
+
+//
+// Debounce with a 50ms quiet time: 1 is replaced by 2 before 50ms pass, so
+// only 2 is sent. 3 is sent when the input closes.
+//
+func TestDebounce(t *testing.T) {
+    before := runtime.NumGoroutine()
+    ctx, cancel := context.WithCancel(context.Background())
+    defer func() {
+        cancel()
+        checkGoroutinesEnd(t, before)
+    }()
+
+    clock := newFakeClock()
+    in := make(chan int)
+    debounced := Debounce(ctx, clock, in, 50*time.Millisecond)
+    in <- 1
+    if !clock.waitTimers(1) {
+        t.Fatalf("no timer for 1")
+    }
+    clock.Advance(30 * time.Millisecond)
+    in <- 2
+    if !clock.waitTimers(2) {
+        t.Fatalf("no timer for 2")
+    }
+    clock.Advance(30 * time.Millisecond) // 60ms since 1, 30ms since 2
+    if ready(debounced) {
+        t.Fatalf("1 wasn't dropped")
+    }
+    clock.Advance(20 * time.Millisecond) // 50ms since 2
+    if x := <-debounced; x != 2 {
+        t.Errorf("got %v, expected 2", x)
+    }
+    in <- 3
+    close(in)
+    if x, ok := <-debounced; !ok || x != 3 {
+        t.Errorf("on close: got %v, %v, expected 3", x, ok)
+    }
+    if _, ok := <-debounced; ok {
+        t.Errorf("debounce didn't close")
+    }
+}
+
+//
+// Timeout of 1s: a value after 999ms is fine, but then waiting a full second
+// stops it.
+//
+func TestTimeout(t *testing.T) {
+    before := runtime.NumGoroutine()
+    ctx, cancel := context.WithCancel(context.Background())
+    defer func() {
+        cancel()
+        checkGoroutinesEnd(t, before)
+    }()
+
+    clock := newFakeClock()
+    in := make(chan int)
+    timed, wait := Timeout(ctx, clock, in, time.Second)
+    go func() { in <- 1 }()
+    if x := <-timed; x != 1 {
+        t.Errorf("got %v, expected 1", x)
+    }
+    if !clock.waitTimers(2) {
+        t.Fatalf("no timer after 1")
+    }
+    clock.Advance(999 * time.Millisecond)
+    go func() { in <- 2 }()
+    if x := <-timed; x != 2 {
+        t.Errorf("got %v, expected 2", x)
+    }
+    if !clock.waitTimers(3) {
+        t.Fatalf("no timer after 2")
+    }
+    clock.Advance(time.Second)
+    if _, ok := <-timed; ok {
+        t.Errorf("didn't time out")
+    }
+    if err := wait(); err != ErrTimeout {
+        t.Errorf("error %v, expected %v", err, ErrTimeout)
+    }
+
+    in = make(chan int)
+    timed, wait = Timeout(ctx, clock, in, time.Second)
+    close(in)
+    if _, ok := <-timed; ok {
+        t.Errorf("didn't close")
+    }
+    if err := wait(); err != nil {
+        t.Errorf("error %v after the input closed normally", err)
+    }
+
+    //
+    // Cancelling ctx stops it with ctx.Err(), both while it's waiting for a
+    // value and while it's waiting to send one.
+    //
+    waitCtx, cancelWait := context.WithCancel(ctx)
+    timed, wait = Timeout(waitCtx, clock, make(chan int), time.Second)
+    cancelWait()
+    if _, ok := <-timed; ok {
+        t.Errorf("didn't stop when cancelled while waiting for a value")
+    }
+    if err := wait(); err != context.Canceled {
+        t.Errorf("error %v when cancelled while waiting for a value, expected %v",
+                 err, context.Canceled)
+    }
+
+    sendCtx, cancelSend := context.WithCancel(ctx)
+    in = make(chan int)
+    timed, wait = Timeout(sendCtx, clock, in, time.Second)
+    in <- 1      // once Timeout has it, it's stuck sending it
+    cancelSend() // since nothing reads from timed
+    if err := wait(); err != context.Canceled {
+        t.Errorf("error %v when cancelled while sending, expected %v", err, context.Canceled)
+    }
+}
+
+//
+// Heartbeat every second.
+//
+func TestHeartbeat(t *testing.T) {
+    before := runtime.NumGoroutine()
+    ctx, cancel := context.WithCancel(context.Background())
+    defer func() {
+        cancel()
+        checkGoroutinesEnd(t, before)
+    }()
+
+    clock := newFakeClock()
+    in := make(chan int)
+    beats := Heartbeat(ctx, clock, in, time.Second)
+    if !clock.waitTimers(1) {
+        t.Fatalf("no timer")
+    }
+    clock.Advance(time.Second)
+    if b := <-beats; !b.Heartbeat {
+        t.Errorf("got %v, expected a heartbeat", b)
+    }
+    go func() { in <- 7 }()
+    if b := <-beats; b.Heartbeat || b.Value != 7 {
+        t.Errorf("got %v, expected 7", b)
+    }
+    if !clock.waitTimers(3) {
+        t.Fatalf("no timer after 7")
+    }
+    clock.Advance(time.Second)
+    if b := <-beats; !b.Heartbeat {
+        t.Errorf("got %v, expected a heartbeat", b)
+    }
+    close(in)
+    if _, ok := <-beats; ok {
+        t.Errorf("didn't close")
+    }
+}
+
+//
+// With the real clock, 10 values at 100 per second (no burst) should take
+// about 90ms.
+//
+func TestRealThrottle(t *testing.T) {
+    ctx, cancel := context.WithCancel(context.Background())
+    defer cancel()
+    start := time.Now()
+    collect(Throttle(ctx, realClock{}, counter(ctx, 10), 100, 1))
+    if elapsed := time.Since(start); elapsed < 85*time.Millisecond || elapsed >= 500*time.Millisecond {
+        t.Errorf("took %v, expected about 90ms", elapsed)
+    }
+}
+
+//
+// Stages waiting on timers must stop when ctx is cancelled.
+//
+func TestThrottleCancel(t *testing.T) {
+    before := runtime.NumGoroutine()
+    ctx, cancel := context.WithCancel(context.Background())
+    clock := newFakeClock()
+    Throttle(ctx, clock, fibgen(ctx), 1, 1)
+    Debounce(ctx, clock, fibgen(ctx), time.Second)
+    Heartbeat(ctx, clock, make(chan int), time.Second)
+    Timeout(ctx, clock, make(chan int), time.Second)
+    cancel()
+    checkGoroutinesEnd(t, before)
+}
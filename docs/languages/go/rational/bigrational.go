@@ -0,0 +1,192 @@
+// bigrational.go
+
+//
+// An arbitrary-precision rational type. The numerator and denominator are
+// *big.Int values from Go's standard math/big package, so they can be as
+// large as memory allows, and nothing ever overflows.
+//
+// math/big also has a rational type, big.Rat, but the assignment asks us not
+// to use pre-made rationals, so only big.Int is used here.
+//
+// BigRational doesn't implement Rationalizer, since Rationalizer's methods
+// return ints, and a big numerator or denominator may not fit in an int.
+//
+
+package main
+
+import (
+    "fmt"
+    "math/big"
+)
+
+//
+// BigRational values are always kept in lowest terms with a positive
+// denominator. Their fields are never modified after they're made, so it's
+// safe for different BigRationals to share the same *big.Int.
+//
+type BigRational struct {
+    num, denom *big.Int
+}
+
+//
+// Returns a new BigRational n/d in lowest terms. n and d are copied, so the
+// caller can change them afterwards. If d is 0, the error is non-nil.
+//
+func makeBigRational(n, d *big.Int) (BigRational, error) {
+    if d.Sign() == 0 {
+        return BigRational{}, ErrZeroDenominator
+    }
+    return bigReduce(new(big.Int).Set(n), new(big.Int).Set(d)), nil
+}
+
+//
+// Returns the BigRational equal to r.
+//
+func bigFromRational(r Rational) BigRational {
+    n, d := r.Split()
+    return bigReduce(big.NewInt(int64(n)), big.NewInt(int64(d)))
+}
+
+//
+// Returns n/d in lowest terms with a positive denominator. n and d are
+// modified, so don't pass in values that are used elsewhere.
+//
+func bigReduce(n, d *big.Int) BigRational {
+    if d.Sign() < 0 {
+        n.Neg(n)
+        d.Neg(d)
+    }
+    g := new(big.Int).GCD(nil, nil, new(big.Int).Abs(n), d)
+    if g.Sign() != 0 {
+        n.Quo(n, g)
+        d.Quo(d, g)
+    }
+    return BigRational{n, d}
+}
+
+func (r BigRational) Numerator() *big.Int {
+    return new(big.Int).Set(r.num)
+}
+
+func (r BigRational) Denominator() *big.Int {
+    return new(big.Int).Set(r.denom)
+}
+
+func (r BigRational) String() string {
+    return r.num.String() + "/" + r.denom.String()
+}
+
+//
+// Returns the float64 closest to r. big.Float is used for the division
+// because r's numerator and denominator may be too big to fit in a float64
+// even when their quotient isn't.
+//
+func (r BigRational) toFloat64() float64 {
+    n := new(big.Float).SetInt(r.num)
+    d := new(big.Float).SetInt(r.denom)
+    f, _ := new(big.Float).Quo(n, d).Float64()
+    return f
+}
+
+//
+// Returns -1 if r < other, 0 if r == other, and 1 if r > other. Both
+// denominators are positive, so cross-multiplying works.
+//
+func (r BigRational) Cmp(other BigRational) int {
+    left := new(big.Int).Mul(r.num, other.denom)
+    right := new(big.Int).Mul(other.num, r.denom)
+    return left.Cmp(right)
+}
+
+//
+// Since BigRationals are always in lowest terms, two are equal exactly when
+// their numerators and denominators are equal.
+//
+func (r BigRational) Equal(other BigRational) bool {
+    return r.num.Cmp(other.num) == 0 && r.denom.Cmp(other.denom) == 0
+}
+
+func (r BigRational) LessThan(other BigRational) bool {
+    return r.Cmp(other) < 0
+}
+
+func (r BigRational) IsInt() bool {
+    return r.denom.Cmp(big.NewInt(1)) == 0
+}
+
+func (r BigRational) Add(other BigRational) BigRational {
+    n := new(big.Int).Mul(r.num, other.denom)
+    n.Add(n, new(big.Int).Mul(other.num, r.denom))
+    d := new(big.Int).Mul(r.denom, other.denom)
+    return bigReduce(n, d)
+}
+
+func (r BigRational) Multiply(other BigRational) BigRational {
+    n := new(big.Int).Mul(r.num, other.num)
+    d := new(big.Int).Mul(r.denom, other.denom)
+    return bigReduce(n, d)
+}
+
+func (r BigRational) Divide(other BigRational) (BigRational, error) {
+    if other.num.Sign() == 0 {
+        return BigRational{}, ErrDivideByZero
+    }
+    n := new(big.Int).Mul(r.num, other.denom)
+    d := new(big.Int).Mul(r.denom, other.num)
+    return bigReduce(n, d), nil
+}
+
+func (r BigRational) Invert() (BigRational, error) {
+    if r.num.Sign() == 0 {
+        return BigRational{}, ErrNoInverse
+    }
+    return bigReduce(new(big.Int).Set(r.denom), new(big.Int).Set(r.num)), nil
+}
+
+//
+// Returns r as a Rational, or ErrOverflow if its numerator or denominator is
+// too big for an int.
+//
+func (r BigRational) toRational() (Rational, error) {
+    if !r.num.IsInt64() || !r.denom.IsInt64() {
+        return Rational{}, ErrOverflow
+    }
+    n, d := r.num.Int64(), r.denom.Int64()
+    if int64(int(n)) != n || int64(int(d)) != d {
+        return Rational{}, ErrOverflow
+    }
+    return Rational{int(n), int(d)}, nil
+}
+
+//
+// Returns the harmonic sum 1/1 + 1/2 + ... + 1/n exactly.
+//
+func bigHarmonicSum(n int) (BigRational, error) {
+    if n <= 0 {
+        return BigRational{}, ErrBadHarmonic
+    }
+    result := bigFromRational(fromInt(0))
+    for i := 1; i <= n; i++ {
+        result = result.Add(bigFromRational(Rational{1, i}))
+    }
+    return result, nil
+}
+
+//
+// Computes H_1, H_2, ..., H_maxN with both the int and big.Int versions, and
+// returns the first n for which the int version overflows (or 0 if none
+// did). It also checks that the two versions agree for every n before that.
+//
+func compareHarmonic(maxN int) (firstOverflow int, err error) {
+    for n := 1; n <= maxN; n++ {
+        want, _ := bigHarmonicSum(n)
+        got, err := harmonicSumChecked(n)
+        if err != nil {
+            return n, nil
+        }
+        if !bigFromRational(got).Equal(want) {
+            return 0, fmt.Errorf("H_%v: int version %v != big version %v", n, got, want)
+        }
+    }
+    return 0, nil
+}
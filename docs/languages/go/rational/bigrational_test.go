@@ -0,0 +1,128 @@
+// bigrational_test.go
+
+//
+// Tests for BigRational.
+//
+
+package main
+
+import (
+    "math/big"
+    "testing"
+)
+
+//
+// Makes a BigRational from known-good ints.
+//
+func mustBig(n, d int64) BigRational {
+    r, err := makeBigRational(big.NewInt(n), big.NewInt(d))
+    if err != nil {
+        panic(err)
+    }
+    return r
+}
+
+func TestBigRational(t *testing.T) {
+    if _, err := makeBigRational(big.NewInt(1), big.NewInt(0)); err != ErrZeroDenominator {
+        t.Errorf("makeBigRational(1, 0) error = %v, expected %v", err, ErrZeroDenominator)
+    }
+
+    stringTests := []struct {
+        r    BigRational
+        want string
+    }{
+        {mustBig(36, 20), "9/5"},
+        {mustBig(36, -20), "-9/5"},
+        {mustBig(-36, -20), "9/5"},
+        {mustBig(0, -7), "0/1"},
+    }
+    for _, tc := range stringTests {
+        if got := tc.r.String(); got != tc.want {
+            t.Errorf("String() = %q, expected %q", got, tc.want)
+        }
+    }
+
+    arithTests := []struct {
+        a, b               BigRational
+        sum, product, quot BigRational
+        less               bool
+    }{
+        {mustBig(1, 2), mustBig(1, 3), mustBig(5, 6), mustBig(1, 6), mustBig(3, 2), false},
+        {mustBig(2, 3), mustBig(-2, 3), mustBig(0, 1), mustBig(-4, 9), mustBig(-1, 1), false},
+        {mustBig(3, -4), mustBig(1, 4), mustBig(-1, 2), mustBig(-3, 16), mustBig(-3, 1), true},
+    }
+    for _, tc := range arithTests {
+        if sum := tc.a.Add(tc.b); !sum.Equal(tc.sum) {
+            t.Errorf("%v + %v = %v, expected %v", tc.a, tc.b, sum, tc.sum)
+        }
+        if product := tc.a.Multiply(tc.b); !product.Equal(tc.product) {
+            t.Errorf("%v * %v = %v, expected %v", tc.a, tc.b, product, tc.product)
+        }
+        if quot, err := tc.a.Divide(tc.b); err != nil || !quot.Equal(tc.quot) {
+            t.Errorf("%v / %v = %v, %v, expected %v", tc.a, tc.b, quot, err, tc.quot)
+        }
+        if less := tc.a.LessThan(tc.b); less != tc.less {
+            t.Errorf("%v < %v is %v, expected %v", tc.a, tc.b, less, tc.less)
+        }
+    }
+
+    if _, err := mustBig(1, 2).Divide(mustBig(0, 1)); err != ErrDivideByZero {
+        t.Errorf("1/2 / 0/1 error = %v, expected %v", err, ErrDivideByZero)
+    }
+    if _, err := mustBig(0, 1).Invert(); err != ErrNoInverse {
+        t.Errorf("0/1.Invert() error = %v, expected %v", err, ErrNoInverse)
+    }
+    if inv, err := mustBig(-2, 3).Invert(); err != nil || !inv.Equal(mustBig(-3, 2)) {
+        t.Errorf("-2/3.Invert() = %v, %v", inv, err)
+    }
+    if !mustBig(4, 2).IsInt() || mustBig(1, 2).IsInt() {
+        t.Errorf("4/2.IsInt() = %v, 1/2.IsInt() = %v", mustBig(4, 2).IsInt(), mustBig(1, 2).IsInt())
+    }
+    if f := mustBig(1, 4).toFloat64(); f != 0.25 {
+        t.Errorf("1/4.toFloat64() = %v", f)
+    }
+}
+
+//
+// Converting back to Rational works only when the result fits.
+//
+func TestBigToRational(t *testing.T) {
+    if r, err := mustBig(-9, 5).toRational(); err != nil || r != (Rational{-9, 5}) {
+        t.Errorf("-9/5.toRational() = %v, %v", r, err)
+    }
+    h, _ := bigHarmonicSum(100)
+    if _, err := h.toRational(); err != ErrOverflow {
+        t.Errorf("H_100.toRational() error = %v, expected %v", err, ErrOverflow)
+    }
+}
+
+//
+// The small harmonic sums must match the int version, and the big ones must
+// still be exact.
+//
+func TestBigHarmonicSum(t *testing.T) {
+    for n := 1; n <= 20; n++ {
+        want, _ := harmonicSum(n)
+        if got, err := bigHarmonicSum(n); err != nil || !got.Equal(bigFromRational(want)) {
+            t.Errorf("bigHarmonicSum(%v) = %v, %v, expected %v", n, got, err, want)
+        }
+    }
+    if _, err := bigHarmonicSum(0); err != ErrBadHarmonic {
+        t.Errorf("bigHarmonicSum(0) error = %v, expected %v", err, ErrBadHarmonic)
+    }
+
+    // H_1000's denominator is lcm(1, 2, ..., 1000) divided by a small factor,
+    // so it has hundreds of digits. Subtracting 1/1000 must give H_999.
+    h1000, _ := bigHarmonicSum(1000)
+    h999, _ := bigHarmonicSum(999)
+    if diff := h1000.Add(mustBig(-1, 1000)); !diff.Equal(h999) {
+        t.Errorf("H_1000 - 1/1000 != H_999")
+    }
+    if f := h1000.toFloat64(); f <= 7.4854 || f >= 7.4855 {
+        t.Errorf("H_1000 = %v, expected about 7.485", f)
+    }
+
+    if n, err := compareHarmonic(100); err != nil || n <= 1 {
+        t.Errorf("compareHarmonic(100) = %v, %v", n, err)
+    }
+}
@@ -0,0 +1,81 @@
+// main.go
+
+//
+// Demonstrates the rational types, and compares the int and big.Int
+// versions of the harmonic sum. go run can't be given the _test.go files, so
+// run it like this:
+//
+//    $ go run $(ls *.go | grep -v _test.go)
+//
+// The tests are in the _test.go files. Run them like this:
+//
+//    $ go test *.go
+//
+
+package main
+
+import (
+    "fmt"
+    "math"
+)
+
+func main() {
+    r, err := makeRational(4, 0)
+    fmt.Printf("makeRational(4, 0) = %v, %v\n", r, err)
+
+    r, _ = makeRational(36, -20)
+    fmt.Printf("%v in lowest terms is %v\n", r, r.ToLowestTerms())
+
+    for _, n := range []int{1, 2, 3, 10, 20} {
+        h, _ := harmonicSum(n)
+        fmt.Printf("H_%v = %v = %v\n", n, h, h.toFloat64())
+    }
+
+    //
+    // harmonicSum silently overflows, while harmonicSumChecked notices.
+    //
+    n, err := compareHarmonic(1000)
+    if err != nil {
+        fmt.Println(err)
+        return
+    }
+    fmt.Printf("harmonicSumChecked first overflows at H_%v\n", n)
+    unchecked, _ := harmonicSum(n)
+    _, err = harmonicSumChecked(n)
+    exact, _ := bigHarmonicSum(n)
+    fmt.Printf("    harmonicSum(%v) = %v (correct: %v)\n",
+        n, unchecked, bigFromRational(unchecked).Equal(exact))
+    fmt.Printf("    harmonicSumChecked(%v) error: %v\n", n, err)
+    fmt.Printf("    bigHarmonicSum(%v) = %v\n", n, exact)
+
+    for _, s := range []string{"1 2/3", "0.1(6)", "-2.125", "22/7"} {
+        r, err := parseRational(s)
+        if err != nil {
+            fmt.Println(err)
+            continue
+        }
+        fmt.Printf("%q = %v = %v = %v, continued fraction %v\n",
+            s, r, r.toMixed(), r.toDecimal(50), r.toContinuedFraction())
+    }
+    for _, maxDenom := range []int{10, 100, 1000, 100000} {
+        r, _ := bestApproximation(math.Pi, maxDenom)
+        fmt.Printf("best approximation of pi with denominator <= %v: %v\n", maxDenom, r)
+    }
+
+    //
+    // Solve  x +  y +  z =  6
+    //            2y + 5z = -4
+    //       2x + 5y -  z = 27
+    //
+    a, _ := makeMatrix([][]int{{1, 1, 1}, {0, 2, 5}, {2, 5, -1}})
+    b := []Rational{fromInt(6), fromInt(-4), fromInt(27)}
+    x, err := solve(a, b)
+    fmt.Printf("solving a*x = %v where a is\n%v\ngives x = %v, err = %v\n", b, a, x, err)
+    inv, err := a.Inverse()
+    fmt.Printf("the inverse of a is\n%v\nerr = %v\n", inv, err)
+
+    h, _ := bigHarmonicSum(1000)
+    s := h.String()
+    fmt.Printf("H_1000 = %v...%v (%v characters) = %v\n",
+        s[:20], s[len(s)-20:], len(s), h.toFloat64())
+}
@@ -0,0 +1,379 @@
+// matrix.go
+
+//
+// Exact linear algebra with rationals: matrix multiplication, determinants,
+// inverses, and solving systems of linear equations with Gaussian
+// elimination.
+//
+// With float64s, Gaussian elimination suffers from round-off error, e.g. it
+// might say x = 0.9999999999999998 when the answer is really x = 1. With
+// rationals every step is exact. All the arithmetic is done with the checked
+// operations from overflow.go, so if a value gets too big for an int, the
+// result is ErrOverflow instead of a wrong answer.
+//
+
+package main
+
+import (
+    "errors"
+    "fmt"
+    "strings"
+)
+
+var (
+    ErrShape     = errors.New("matrix: sizes don't match")
+    ErrNotSquare = errors.New("matrix: matrix is not square")
+    ErrSingular  = errors.New("matrix: matrix is singular")
+)
+
+//
+// A rows x cols matrix of rationals. data[i][j] is the entry in row i and
+// column j, and is always in lowest terms.
+//
+type Matrix struct {
+    rows, cols int
+    data       [][]Rational
+}
+
+//
+// Returns a new matrix with the given rows of ints, e.g.
+//
+//    makeMatrix([][]int{{1, 2}, {3, 4}})
+//
+// The error is ErrShape if the rows aren't all the same length, or there
+// are no rows or columns.
+//
+func makeMatrix(rows [][]int) (Matrix, error) {
+    rats := make([][]Rational, len(rows))
+    for i, row := range rows {
+        rats[i] = make([]Rational, len(row))
+        for j, x := range row {
+            rats[i][j] = fromInt(x)
+        }
+    }
+    return makeRationalMatrix(rats)
+}
+
+//
+// Like makeMatrix, but the entries are rationals. The entries are copied,
+// and put in lowest terms.
+//
+func makeRationalMatrix(rows [][]Rational) (Matrix, error) {
+    if len(rows) == 0 || len(rows[0]) == 0 {
+        return Matrix{}, ErrShape
+    }
+    m := zeroMatrix(len(rows), len(rows[0]))
+    for i, row := range rows {
+        if len(row) != m.cols {
+            return Matrix{}, fmt.Errorf("%w: row %v has %v entries, not %v",
+                ErrShape, i, len(row), m.cols)
+        }
+        for j, x := range row {
+            r, err := reduceChecked(x.Split())
+            if err != nil {
+                return Matrix{}, err
+            }
+            m.data[i][j] = r
+        }
+    }
+    return m, nil
+}
+
+//
+// Returns a rows x cols matrix of 0s.
+//
+func zeroMatrix(rows, cols int) Matrix {
+    data := make([][]Rational, rows)
+    for i := range data {
+        data[i] = make([]Rational, cols)
+        for j := range data[i] {
+            data[i][j] = fromInt(0)
+        }
+    }
+    return Matrix{rows, cols, data}
+}
+
+//
+// Returns the n x n identity matrix.
+//
+func identity(n int) Matrix {
+    m := zeroMatrix(n, n)
+    for i := 0; i < n; i++ {
+        m.data[i][i] = fromInt(1)
+    }
+    return m
+}
+
+//
+// Returns the entry in row i, column j.
+//
+func (m Matrix) At(i, j int) Rational {
+    return m.data[i][j]
+}
+
+//
+// Returns true if m and other are the same size and have the same entries.
+//
+func (m Matrix) Equal(other Matrix) bool {
+    if m.rows != other.rows || m.cols != other.cols {
+        return false
+    }
+    for i := range m.data {
+        for j := range m.data[i] {
+            if !m.data[i][j].Equal(other.data[i][j]) {
+                return false
+            }
+        }
+    }
+    return true
+}
+
+//
+// Returns the matrix as lines of right-aligned columns, e.g.
+//
+//    [   1  1/2 ]
+//    [ -3/4   2 ]
+//
+// Integers are shown without a denominator.
+//
+func (m Matrix) String() string {
+    cells := make([][]string, m.rows)
+    width := 0
+    for i, row := range m.data {
+        cells[i] = make([]string, m.cols)
+        for j, x := range row {
+            cells[i][j] = shortString(x)
+            width = max(width, len(cells[i][j]))
+        }
+    }
+    var sb strings.Builder
+    for i, row := range cells {
+        sb.WriteString("[")
+        for _, c := range row {
+            fmt.Fprintf(&sb, " %*s", width, c)
+        }
+        sb.WriteString(" ]")
+        if i < len(cells)-1 {
+            sb.WriteString("\n")
+        }
+    }
+    return sb.String()
+}
+
+//
+// Returns "n" if r is an integer, and "n/d" otherwise.
+//
+func shortString(r Rational) string {
+    if r.denom == 1 {
+        return fmt.Sprint(r.num)
+    }
+    return r.String()
+}
+
+//
+// Returns a copy of m's entries, so they can be modified without changing
+// m.
+//
+func (m Matrix) copyData() [][]Rational {
+    data := make([][]Rational, m.rows)
+    for i, row := range m.data {
+        data[i] = append([]Rational{}, row...)
+    }
+    return data
+}
+
+//
+// Returns the matrix product m * other. The error is ErrShape if m's number
+// of columns isn't the same as other's number of rows.
+//
+func (m Matrix) Multiply(other Matrix) (Matrix, error) {
+    if m.cols != other.rows {
+        return Matrix{}, fmt.Errorf("%w: can't multiply %vx%v by %vx%v",
+            ErrShape, m.rows, m.cols, other.rows, other.cols)
+    }
+    result := zeroMatrix(m.rows, other.cols)
+    for i := 0; i < m.rows; i++ {
+        for j := 0; j < other.cols; j++ {
+            sum := fromInt(0)
+            for k := 0; k < m.cols; k++ {
+                p, err := m.data[i][k].MultiplyChecked(other.data[k][j])
+                if err != nil {
+                    return Matrix{}, err
+                }
+                if sum, err = sum.AddChecked(p); err != nil {
+                    return Matrix{}, err
+                }
+            }
+            result.data[i][j] = sum
+        }
+    }
+    return result, nil
+}
+
+//
+// Converts the first ncols columns of a to reduced row echelon form using
+// Gauss-Jordan elimination, i.e. each pivot is 1 and is the only non-zero
+// entry in its column. The same row operations are applied to any columns
+// after the first ncols, which is how augmented matrices like [A | b] are
+// solved. a is modified.
+//
+// Returns the column of each pivot, in order, and the determinant of the
+// first ncols columns (only meaningful if they're square). The determinant
+// is the product of the pivots before they're scaled to 1, with the sign
+// flipped for every row swap.
+//
+func gaussJordan(a [][]Rational, ncols int) (pivotCols []int, det Rational, err error) {
+    det = fromInt(1)
+    row := 0
+    for col := 0; col < ncols && row < len(a); col++ {
+        //
+        // Find a row at or below row with a non-zero entry in this column.
+        // There's no round-off with rationals, so unlike with floats any
+        // non-zero pivot is fine.
+        //
+        p := row
+        for p < len(a) && a[p][col].num == 0 {
+            p++
+        }
+        if p == len(a) {
+            continue // no pivot in this column
+        }
+        if p != row {
+            a[p], a[row] = a[row], a[p]
+            det = Rational{-det.num, det.denom}
+        }
+
+        //
+        // Scale the pivot row so the pivot is 1.
+        //
+        pivot := a[row][col]
+        if det, err = det.MultiplyChecked(pivot); err != nil {
+            return nil, Rational{}, err
+        }
+        for j := col; j < len(a[row]); j++ {
+            if a[row][j], err = a[row][j].DivideChecked(pivot); err != nil {
+                return nil, Rational{}, err
+            }
+        }
+
+        //
+        // Subtract multiples of the pivot row from every other row to make
+        // the rest of the column 0.
+        //
+        for i := range a {
+            factor := a[i][col]
+            if i == row || factor.num == 0 {
+                continue
+            }
+            for j := col; j < len(a[i]); j++ {
+                p, err := factor.MultiplyChecked(a[row][j])
+                if err != nil {
+                    return nil, Rational{}, err
+                }
+                if a[i][j], err = a[i][j].AddChecked(Rational{-p.num, p.denom}); err != nil {
+                    return nil, Rational{}, err
+                }
+            }
+        }
+        pivotCols = append(pivotCols, col)
+        row++
+    }
+    if len(pivotCols) < ncols {
+        det = fromInt(0)
+    }
+    return pivotCols, det, nil
+}
+
+//
+// Returns the determinant of m. The error is ErrNotSquare if m isn't
+// square.
+//
+func (m Matrix) Determinant() (Rational, error) {
+    if m.rows != m.cols {
+        return Rational{}, ErrNotSquare
+    }
+    _, det, err := gaussJordan(m.copyData(), m.cols)
+    return det, err
+}
+
+//
+// Returns the inverse of m, i.e. the matrix that gives the identity when
+// multiplied by m. The error is ErrNotSquare if m isn't square, and
+// ErrSingular if m has no inverse.
+//
+// The inverse is found by row reducing [m | I]; when the left half becomes
+// I, the right half is the inverse.
+//
+func (m Matrix) Inverse() (Matrix, error) {
+    if m.rows != m.cols {
+        return Matrix{}, ErrNotSquare
+    }
+    n := m.rows
+    aug := m.copyData()
+    id := identity(n)
+    for i := range aug {
+        aug[i] = append(aug[i], id.data[i]...)
+    }
+    pivotCols, _, err := gaussJordan(aug, n)
+    if err != nil {
+        return Matrix{}, err
+    }
+    if len(pivotCols) < n {
+        return Matrix{}, ErrSingular
+    }
+    result := zeroMatrix(n, n)
+    for i := range aug {
+        copy(result.data[i], aug[i][n:])
+    }
+    return result, nil
+}
+
+//
+// Returns the exact solution x of the system of linear equations a*x = b.
+// For example, the system
+//
+//    2x +  y = 5
+//     x - 3y = -1
+//
+// is solved by solve(a, b) where a is [[2, 1], [1, -3]] and b is [5, -1].
+// a doesn't need to be square, but the system must have exactly one
+// solution. If it has none, or infinitely many, the error wraps
+// ErrSingular and says which.
+//
+func solve(a Matrix, b []Rational) ([]Rational, error) {
+    if len(b) != a.rows {
+        return nil, fmt.Errorf("%w: %v equations but %v right-hand sides",
+            ErrShape, a.rows, len(b))
+    }
+    aug := a.copyData()
+    for i := range aug {
+        r, err := reduceChecked(b[i].Split())
+        if err != nil {
+            return nil, err
+        }
+        aug[i] = append(aug[i], r)
+    }
+    pivotCols, _, err := gaussJordan(aug, a.cols)
+    if err != nil {
+        return nil, err
+    }
+
+    //
+    // Rows below the last pivot have all 0s on the left. If one of them has
+    // a non-zero right-hand side, it says 0 = c, which is impossible.
+    //
+    for i := len(pivotCols); i < len(aug); i++ {
+        if aug[i][a.cols].num != 0 {
+            return nil, fmt.Errorf("%w: the system has no solution", ErrSingular)
+        }
+    }
+    if len(pivotCols) < a.cols {
+        return nil, fmt.Errorf("%w: the system has infinitely many solutions", ErrSingular)
+    }
+
+    x := make([]Rational, a.cols)
+    for i, col := range pivotCols {
+        x[col] = aug[i][a.cols]
+    }
+    return x, nil
+}
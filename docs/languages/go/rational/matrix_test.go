@@ -0,0 +1,197 @@
+// matrix_test.go
+
+//
+// Tests for Matrix.
+//
+
+package main
+
+import (
+    "errors"
+    "slices"
+    "testing"
+)
+
+//
+// Makes a matrix from known-good rows.
+//
+func mustMatrix(rows [][]int) Matrix {
+    m, err := makeMatrix(rows)
+    if err != nil {
+        panic(err)
+    }
+    return m
+}
+
+func equalRationals(a, b Rational) bool {
+    return a.Equal(b)
+}
+
+//
+// Returns rationals equal to the ints xs.
+//
+func ints(xs ...int) []Rational {
+    result := make([]Rational, len(xs))
+    for i, x := range xs {
+        result[i] = fromInt(x)
+    }
+    return result
+}
+
+func TestMakeMatrix(t *testing.T) {
+    if _, err := makeMatrix([][]int{{1, 2}, {3}}); !errors.Is(err, ErrShape) {
+        t.Errorf("ragged rows: error = %v, expected %v", err, ErrShape)
+    }
+    if _, err := makeMatrix(nil); !errors.Is(err, ErrShape) {
+        t.Errorf("no rows: error = %v, expected %v", err, ErrShape)
+    }
+    if _, err := makeRationalMatrix([][]Rational{{Rational{1, 0}}}); err != ErrZeroDenominator {
+        t.Errorf("0 denominator: error = %v, expected %v", err, ErrZeroDenominator)
+    }
+    m, err := makeRationalMatrix([][]Rational{{Rational{2, 4}, Rational{3, -6}}})
+    if err != nil || m.At(0, 0) != (Rational{1, 2}) || m.At(0, 1) != (Rational{-1, 2}) {
+        t.Errorf("makeRationalMatrix didn't reduce: got\n%v, %v", m, err)
+    }
+}
+
+func TestMultiply(t *testing.T) {
+    tests := []struct {
+        a, b, want Matrix
+    }{
+        {mustMatrix([][]int{{1, 2}, {3, 4}}), mustMatrix([][]int{{5, 6}, {7, 8}}),
+         mustMatrix([][]int{{19, 22}, {43, 50}})},
+        {mustMatrix([][]int{{1, 2, 3}}), mustMatrix([][]int{{4}, {5}, {6}}),
+         mustMatrix([][]int{{32}})},
+        {mustMatrix([][]int{{4}, {5}}), mustMatrix([][]int{{1, 2}}),
+         mustMatrix([][]int{{4, 8}, {5, 10}})},
+        {mustMatrix([][]int{{1, 2}, {3, 4}}), identity(2),
+         mustMatrix([][]int{{1, 2}, {3, 4}})},
+    }
+    for _, tc := range tests {
+        if got, err := tc.a.Multiply(tc.b); err != nil || !got.Equal(tc.want) {
+            t.Errorf("multiplying\n%v\nby\n%v\ngave\n%v\n%v", tc.a, tc.b, got, err)
+        }
+    }
+    if _, err := identity(2).Multiply(identity(3)); !errors.Is(err, ErrShape) {
+        t.Errorf("multiplying 2x2 by 3x3: error = %v, expected %v", err, ErrShape)
+    }
+}
+
+func TestDeterminant(t *testing.T) {
+    tests := []struct {
+        m    Matrix
+        want Rational
+    }{
+        {mustMatrix([][]int{{5}}), Rational{5, 1}},
+        {mustMatrix([][]int{{1, 2}, {3, 4}}), Rational{-2, 1}},
+        {mustMatrix([][]int{{0, 1}, {1, 0}}), Rational{-1, 1}},
+        {mustMatrix([][]int{{2, 0, 1}, {1, 3, 2}, {1, 1, 2}}), Rational{6, 1}},
+        {mustMatrix([][]int{{2, 0, 1}, {1, 3, 2}, {1, 1, 1}}), Rational{0, 1}},
+        {mustMatrix([][]int{{1, 2}, {2, 4}}), Rational{0, 1}},
+        {mustMatrix([][]int{{0, 0}, {0, 0}}), Rational{0, 1}},
+        {identity(4), Rational{1, 1}},
+        {mustMatrix([][]int{{6, 1, 1}, {4, -2, 5}, {2, 8, 7}}), Rational{-306, 1}},
+    }
+    for _, tc := range tests {
+        if got, err := tc.m.Determinant(); err != nil || !got.Equal(tc.want) {
+            t.Errorf("determinant of\n%v\n= %v, %v, expected %v", tc.m, got, err, tc.want)
+        }
+    }
+    if _, err := mustMatrix([][]int{{1, 2}}).Determinant(); err != ErrNotSquare {
+        t.Errorf("determinant of 1x2: error = %v, expected %v", err, ErrNotSquare)
+    }
+}
+
+func TestInverse(t *testing.T) {
+    tests := []struct {
+        m, want Matrix
+    }{
+        {mustMatrix([][]int{{2}}), Matrix{1, 1, [][]Rational{{{1, 2}}}}},
+        {mustMatrix([][]int{{4, 7}, {2, 6}}), Matrix{2, 2, [][]Rational{
+            {{3, 5}, {-7, 10}},
+            {{-1, 5}, {2, 5}}}}},
+        {mustMatrix([][]int{{0, 1}, {1, 0}}), mustMatrix([][]int{{0, 1}, {1, 0}})},
+        {mustMatrix([][]int{{2, 0, 1}, {1, 3, 2}, {1, 1, 2}}), Matrix{3, 3, [][]Rational{
+            {{2, 3}, {1, 6}, {-1, 2}},
+            {{0, 1}, {1, 2}, {-1, 2}},
+            {{-1, 3}, {-1, 3}, {1, 1}}}}},
+    }
+    for _, tc := range tests {
+        inv, err := tc.m.Inverse()
+        if err != nil || !inv.Equal(tc.want) {
+            t.Errorf("inverse of\n%v\n=\n%v\n%v", tc.m, inv, err)
+            continue
+        }
+        if product, err := tc.m.Multiply(inv); err != nil || !product.Equal(identity(tc.m.rows)) {
+            t.Errorf("m * inverse of\n%v\n=\n%v\n%v", tc.m, product, err)
+        }
+    }
+    if _, err := mustMatrix([][]int{{1, 2}, {2, 4}}).Inverse(); err != ErrSingular {
+        t.Errorf("inverse of singular matrix: error = %v, expected %v", err, ErrSingular)
+    }
+    if _, err := mustMatrix([][]int{{1, 2, 3}}).Inverse(); err != ErrNotSquare {
+        t.Errorf("inverse of 1x3: error = %v, expected %v", err, ErrNotSquare)
+    }
+}
+
+func TestSolve(t *testing.T) {
+    tests := []struct {
+        a       Matrix
+        b       []Rational
+        want    []Rational
+        wantErr error
+    }{
+        // 2x + y = 5, x - 3y = -1
+        {mustMatrix([][]int{{2, 1}, {1, -3}}), ints(5, -1), ints(2, 1), nil},
+        // answers that aren't integers
+        {mustMatrix([][]int{{3, 2}, {1, 2}}), ints(1, 1), []Rational{{0, 1}, {1, 2}}, nil},
+        {mustMatrix([][]int{{1, 1}, {1, -1}}), ints(1, 0), []Rational{{1, 2}, {1, 2}}, nil},
+        // needs a row swap: the first pivot is 0
+        {mustMatrix([][]int{{0, 1}, {1, 0}}), ints(3, 4), ints(4, 3), nil},
+        // 3 equations
+        {mustMatrix([][]int{{1, 1, 1}, {0, 2, 5}, {2, 5, -1}}), ints(6, -4, 27), ints(5, 3, -2), nil},
+        // more equations than unknowns, but consistent
+        {mustMatrix([][]int{{1, 0}, {0, 1}, {1, 1}}), ints(2, 3, 5), ints(2, 3), nil},
+        // no solution: x + y = 1 and x + y = 2
+        {mustMatrix([][]int{{1, 1}, {1, 1}}), ints(1, 2), nil, ErrSingular},
+        // infinitely many solutions
+        {mustMatrix([][]int{{1, 1}, {2, 2}}), ints(1, 2), nil, ErrSingular},
+        {mustMatrix([][]int{{1, 1, 1}}), ints(1), nil, ErrSingular},
+        // wrong size of b
+        {mustMatrix([][]int{{1, 1}, {2, 2}}), ints(1), nil, ErrShape},
+    }
+    for _, tc := range tests {
+        got, err := solve(tc.a, tc.b)
+        if tc.wantErr != nil {
+            if !errors.Is(err, tc.wantErr) {
+                t.Errorf("solving\n%v\nwith b = %v: error = %v, expected %v", tc.a, tc.b, err, tc.wantErr)
+            }
+        } else if err != nil || !slices.EqualFunc(got, tc.want, equalRationals) {
+            t.Errorf("solving\n%v\nwith b = %v gave %v, %v, expected %v", tc.a, tc.b, got, err, tc.want)
+        }
+    }
+}
+
+//
+// The Hilbert matrix, with entries 1/(i+j+1), is a famous example of a
+// matrix that floats can't invert accurately. Rationals get it exactly.
+//
+func TestHilbert(t *testing.T) {
+    hilbert := zeroMatrix(5, 5)
+    for i := 0; i < 5; i++ {
+        for j := 0; j < 5; j++ {
+            hilbert.data[i][j] = Rational{1, i + j + 1}
+        }
+    }
+    hinv, err := hilbert.Inverse()
+    if err != nil {
+        t.Fatalf("inverting the Hilbert matrix: %v", err)
+    }
+    product, _ := hilbert.Multiply(hinv)
+    if !product.Equal(identity(5)) || hinv.At(0, 0) != (Rational{25, 1}) || hinv.At(4, 4) != (Rational{44100, 1}) {
+        t.Errorf("wrong inverse of the Hilbert matrix:\n%v", hinv)
+    }
+    if det, err := hilbert.Determinant(); err != nil || det != (Rational{1, 266716800000}) {
+        t.Errorf("Hilbert determinant = %v, %v, expected 1/266716800000", det, err)
+    }
+}
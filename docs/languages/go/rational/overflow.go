@@ -0,0 +1,205 @@
+// overflow.go
+
+//
+// Go's int arithmetic silently wraps around when a result is too big, e.g.
+// math.MaxInt + 1 is math.MinInt. Rational arithmetic multiplies numerators
+// and denominators together, so values like harmonic sums overflow quickly.
+//
+// The functions here do the same arithmetic as in rational.go, but check
+// every int operation and return ErrOverflow instead of a wrong answer.
+//
+
+package main
+
+import (
+    "errors"
+    "fmt"
+    "math"
+    "math/big"
+)
+
+var ErrOverflow = errors.New("rational: integer overflow")
+
+//
+// Returns a + b, or ErrOverflow if the sum doesn't fit in an int.
+//
+func addInt(a, b int) (int, error) {
+    c := a + b
+    // Adding a positive number must make a bigger result, and adding a
+    // negative number must make a smaller one. If not, it wrapped around.
+    if (b > 0 && c < a) || (b < 0 && c > a) {
+        return 0, ErrOverflow
+    }
+    return c, nil
+}
+
+//
+// Returns a * b, or ErrOverflow if the product doesn't fit in an int.
+//
+func mulInt(a, b int) (int, error) {
+    if a == 0 || b == 0 {
+        return 0, nil
+    }
+    c := a * b
+    // math.MinInt * -1 wraps around to math.MinInt, and dividing
+    // math.MinInt by -1 wraps in the same way, so check that case first.
+    if (a == -1 && b == math.MinInt) || (b == -1 && a == math.MinInt) {
+        return 0, ErrOverflow
+    }
+    if c/b != a {
+        return 0, ErrOverflow
+    }
+    return c, nil
+}
+
+//
+// Returns -a, or ErrOverflow if a is math.MinInt (which has no positive
+// int equal to its negation).
+//
+func negInt(a int) (int, error) {
+    if a == math.MinInt {
+        return 0, ErrOverflow
+    }
+    return -a, nil
+}
+
+//
+// Like reduce, but returns ErrOverflow instead of wrapping around when the
+// signs are flipped, e.g. for 1/math.MinInt.
+//
+func reduceChecked(n, d int) (Rational, error) {
+    if d == 0 {
+        return Rational{}, ErrZeroDenominator
+    }
+    g := gcd(n, d)
+    n, d = n/g, d/g
+    if d < 0 {
+        var err error
+        if n, err = negInt(n); err != nil {
+            return Rational{}, err
+        }
+        if d, err = negInt(d); err != nil {
+            return Rational{}, err
+        }
+    }
+    return Rational{n, d}, nil
+}
+
+//
+// Returns r + other in lowest terms, or ErrOverflow.
+//
+// Instead of using a/b + c/d = (ad + bc)/bd directly, both values are first
+// put in lowest terms, and the sum is put over the least common multiple of
+// b and d. This keeps the intermediate values as small as possible, so it
+// only overflows when it really must.
+//
+func (r Rational) AddChecked(other Rationalizer) (Rational, error) {
+    x, err := reduceChecked(r.Split())
+    if err != nil {
+        return Rational{}, err
+    }
+    y, err := reduceChecked(other.Split())
+    if err != nil {
+        return Rational{}, err
+    }
+    g := gcd(x.denom, y.denom)
+    left, err := mulInt(x.num, y.denom/g)
+    if err != nil {
+        return Rational{}, err
+    }
+    right, err := mulInt(y.num, x.denom/g)
+    if err != nil {
+        return Rational{}, err
+    }
+    n, err := addInt(left, right)
+    if err != nil {
+        return Rational{}, err
+    }
+    d, err := mulInt(x.denom, y.denom/g)
+    if err != nil {
+        return Rational{}, err
+    }
+    return reduceChecked(n, d)
+}
+
+//
+// Returns r * other in lowest terms, or ErrOverflow.
+//
+// Common factors are cancelled across the two fractions before multiplying,
+// e.g. 4/9 * 3/8 is done as 1/3 * 1/2.
+//
+func (r Rational) MultiplyChecked(other Rationalizer) (Rational, error) {
+    x, err := reduceChecked(r.Split())
+    if err != nil {
+        return Rational{}, err
+    }
+    y, err := reduceChecked(other.Split())
+    if err != nil {
+        return Rational{}, err
+    }
+    if x.num == 0 || y.num == 0 {
+        return fromInt(0), nil
+    }
+    g1 := gcd(x.num, y.denom)
+    g2 := gcd(y.num, x.denom)
+    n, err := mulInt(x.num/g1, y.num/g2)
+    if err != nil {
+        return Rational{}, err
+    }
+    d, err := mulInt(x.denom/g2, y.denom/g1)
+    if err != nil {
+        return Rational{}, err
+    }
+    return reduceChecked(n, d)
+}
+
+//
+// Returns r / other in lowest terms. The error is ErrDivideByZero if other
+// is 0, or ErrOverflow if the result doesn't fit.
+//
+func (r Rational) DivideChecked(other Rationalizer) (Rational, error) {
+    c, d := other.Split()
+    if c == 0 {
+        return Rational{}, ErrDivideByZero
+    }
+    return r.MultiplyChecked(Rational{d, c})
+}
+
+//
+// Like harmonicSum, but returns ErrOverflow if H_n can't be represented with
+// ints.
+//
+func harmonicSumChecked(n int) (Rational, error) {
+    if n <= 0 {
+        return Rational{}, ErrBadHarmonic
+    }
+    result := fromInt(0)
+    for i := 1; i <= n; i++ {
+        var err error
+        result, err = result.AddChecked(Rational{1, i})
+        if err != nil {
+            return Rational{}, fmt.Errorf("harmonicSumChecked(%v) at 1/%v: %w", n, i, err)
+        }
+    }
+    return result, nil
+}
+
+//
+// Returns -1, 0, or 1 depending on whether an*bd is less than, equal to, or
+// greater than bn*ad. If either product overflows, then it's re-done using
+// big.Int, so the answer is always correct.
+//
+func crossCompare(an, ad, bn, bd int) int {
+    left, err1 := mulInt(an, bd)
+    right, err2 := mulInt(bn, ad)
+    if err1 == nil && err2 == nil {
+        switch {
+        case left < right: return -1
+        case left > right: return 1
+        default:           return 0
+        }
+    }
+    bigLeft := new(big.Int).Mul(big.NewInt(int64(an)), big.NewInt(int64(bd)))
+    bigRight := new(big.Int).Mul(big.NewInt(int64(bn)), big.NewInt(int64(ad)))
+    return bigLeft.Cmp(bigRight)
+}
@@ -0,0 +1,116 @@
+// overflow_test.go
+
+//
+// Tests for the checked arithmetic.
+//
+
+package main
+
+import (
+    "errors"
+    "math"
+    "testing"
+)
+
+func TestIntOverflow(t *testing.T) {
+    tests := []struct {
+        name     string
+        f        func(int, int) (int, error)
+        a, b     int
+        want     int
+        overflow bool
+    }{
+        {"addInt", addInt, 1, 2, 3, false},
+        {"addInt", addInt, math.MaxInt, 0, math.MaxInt, false},
+        {"addInt", addInt, math.MaxInt, 1, 0, true},
+        {"addInt", addInt, math.MinInt, -1, 0, true},
+        {"addInt", addInt, math.MinInt, math.MaxInt, -1, false},
+        {"mulInt", mulInt, 6, 7, 42, false},
+        {"mulInt", mulInt, -6, 7, -42, false},
+        {"mulInt", mulInt, math.MaxInt, 2, 0, true},
+        {"mulInt", mulInt, math.MinInt, -1, 0, true},
+        {"mulInt", mulInt, -1, math.MinInt, 0, true},
+        {"mulInt", mulInt, math.MinInt, 1, math.MinInt, false},
+        {"mulInt", mulInt, 1 << 32, 1 << 32, 0, true},
+        {"mulInt", mulInt, 0, math.MinInt, 0, false},
+    }
+    for _, tc := range tests {
+        got, err := tc.f(tc.a, tc.b)
+        if tc.overflow {
+            if err != ErrOverflow {
+                t.Errorf("%v(%v, %v) = %v, %v, expected ErrOverflow", tc.name, tc.a, tc.b, got, err)
+            }
+        } else if err != nil || got != tc.want {
+            t.Errorf("%v(%v, %v) = %v, %v, expected %v", tc.name, tc.a, tc.b, got, err, tc.want)
+        }
+    }
+}
+
+func TestCheckedArithmetic(t *testing.T) {
+    tests := []struct {
+        name     string
+        f        func(Rational, Rationalizer) (Rational, error)
+        a, b     Rational
+        want     Rational
+        wantErr  error
+    }{
+        {"AddChecked", Rational.AddChecked, Rational{1, 2}, Rational{1, 3}, Rational{5, 6}, nil},
+        {"AddChecked", Rational.AddChecked, Rational{1, 6}, Rational{1, -3}, Rational{-1, 6}, nil},
+        {"AddChecked", Rational.AddChecked, Rational{math.MaxInt, 1}, Rational{1, 1}, Rational{}, ErrOverflow},
+        {"AddChecked", Rational.AddChecked, Rational{1, 0}, Rational{1, 2}, Rational{}, ErrZeroDenominator},
+        {"AddChecked", Rational.AddChecked, Rational{1, math.MaxInt}, Rational{1, math.MaxInt - 1}, Rational{}, ErrOverflow},
+        {"AddChecked", Rational.AddChecked, Rational{math.MinInt, 6}, Rational{0, 1}, Rational{math.MinInt / 2, 3}, nil},
+        {"AddChecked", Rational.AddChecked, Rational{1, math.MinInt}, Rational{0, 1}, Rational{}, ErrOverflow},
+        {"MultiplyChecked", Rational.MultiplyChecked, Rational{4, 9}, Rational{3, 8}, Rational{1, 6}, nil},
+        {"MultiplyChecked", Rational.MultiplyChecked, Rational{math.MaxInt, 2}, Rational{2, 3}, Rational{math.MaxInt, 3}, nil},
+        {"MultiplyChecked", Rational.MultiplyChecked, Rational{math.MaxInt, 1}, Rational{2, 1}, Rational{}, ErrOverflow},
+        {"MultiplyChecked", Rational.MultiplyChecked, Rational{0, 5}, Rational{math.MaxInt, 1}, Rational{0, 1}, nil},
+        {"MultiplyChecked", Rational.MultiplyChecked, Rational{math.MinInt, 1}, Rational{1, 1}, Rational{math.MinInt, 1}, nil},
+        {"MultiplyChecked", Rational.MultiplyChecked, Rational{math.MinInt, -1}, Rational{1, 1}, Rational{}, ErrOverflow},
+        {"MultiplyChecked", Rational.MultiplyChecked, Rational{math.MinInt, -6}, Rational{1, 1}, Rational{-(math.MinInt / 2), 3}, nil},
+        {"DivideChecked", Rational.DivideChecked, Rational{1, 2}, Rational{3, 4}, Rational{2, 3}, nil},
+        {"DivideChecked", Rational.DivideChecked, Rational{1, 2}, Rational{0, 4}, Rational{}, ErrDivideByZero},
+        {"DivideChecked", Rational.DivideChecked, Rational{math.MaxInt, 1}, Rational{1, 2}, Rational{}, ErrOverflow},
+    }
+    for _, tc := range tests {
+        got, err := tc.f(tc.a, tc.b)
+        if tc.wantErr != nil {
+            if !errors.Is(err, tc.wantErr) {
+                t.Errorf("%v(%v, %v) = %v, %v, expected error %v", tc.name, tc.a, tc.b, got, err, tc.wantErr)
+            }
+        } else if err != nil || got != tc.want {
+            t.Errorf("%v(%v, %v) = %v, %v, expected %v", tc.name, tc.a, tc.b, got, err, tc.want)
+        }
+    }
+}
+
+//
+// Comparisons that overflow the cross-multiplication must still give the
+// right answer.
+//
+func TestBigComparisons(t *testing.T) {
+    big1 := Rational{math.MaxInt, math.MaxInt - 1} // a little more than 1
+    big2 := Rational{math.MaxInt - 1, math.MaxInt} // a little less than 1
+    if !big2.LessThan(big1) || big1.LessThan(big2) {
+        t.Errorf("%v < %v is %v, and %v < %v is %v",
+                 big2, big1, big2.LessThan(big1), big1, big2, big1.LessThan(big2))
+    }
+    if big1.Equal(big2) {
+        t.Errorf("%v.Equal(%v) is true", big1, big2)
+    }
+    if !big1.Equal(Rational{math.MaxInt, math.MaxInt - 1}) {
+        t.Errorf("%v doesn't equal itself", big1)
+    }
+}
+
+func TestHarmonicSumChecked(t *testing.T) {
+    if h, err := harmonicSumChecked(10); err != nil || h != (Rational{7381, 2520}) {
+        t.Errorf("harmonicSumChecked(10) = %v, %v", h, err)
+    }
+    if _, err := harmonicSumChecked(1000); !errors.Is(err, ErrOverflow) {
+        t.Errorf("harmonicSumChecked(1000) error = %v, expected ErrOverflow", err)
+    }
+    if _, err := harmonicSumChecked(-1); err != ErrBadHarmonic {
+        t.Errorf("harmonicSumChecked(-1) error = %v, expected %v", err, ErrBadHarmonic)
+    }
+}
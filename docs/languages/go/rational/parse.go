@@ -0,0 +1,457 @@
+// parse.go
+
+//
+// Converting rationals to and from other forms:
+//
+// - parsing strings like "5/3", "1 2/3" (a mixed number), "2.5" and "0.1(6)"
+//   (a repeating decimal, i.e. 0.1666...)
+// - formatting rationals as mixed numbers and as decimals, with the
+//   repeating part of the decimal in ()-brackets
+// - converting to and from continued fractions, and finding the best
+//   rational approximation of a float64
+//
+// The parsing is done with BigRational, so a string with a lot of digits
+// gives ErrOverflow rather than a wrong answer.
+//
+
+package main
+
+import (
+    "errors"
+    "fmt"
+    "math"
+    "math/big"
+    "strings"
+)
+
+var (
+    ErrSyntax    = errors.New("rational: invalid syntax")
+    ErrNotFinite = errors.New("rational: value is NaN or infinite")
+    ErrNoTerms   = errors.New("rational: continued fraction has no terms")
+)
+
+//
+// Returns the Rational that s represents. These forms are allowed:
+//
+//    "7", "-7"            integers
+//    "5/3", "-5/3", "5/-3" fractions
+//    "1 2/3", "-1 2/3"    mixed numbers, i.e. 1 + 2/3 and -(1 + 2/3)
+//    "2.5", "-.25"        decimals
+//    "0.1(6)", "1.(3)"    decimals whose ()-bracketed digits repeat forever
+//
+// Extra spaces, and spaces around the /, are ignored. The result is in
+// lowest terms.
+//
+func parseRational(s string) (Rational, error) {
+    b, err := parseBigRational(s)
+    if err != nil {
+        return Rational{}, err
+    }
+    r, err := b.toRational()
+    if err != nil {
+        return Rational{}, fmt.Errorf("parsing %q: %w", s, err)
+    }
+    return r, nil
+}
+
+//
+// Like parseRational, but returns a BigRational, so there's no limit to the
+// number of digits.
+//
+func parseBigRational(s string) (BigRational, error) {
+    syntaxErr := fmt.Errorf("%w: %q", ErrSyntax, s)
+
+    //
+    // Squash runs of spaces down to one space, and remove spaces around /,
+    // so "  1  2 / 3 " becomes "1 2/3".
+    //
+    s = strings.Join(strings.Fields(s), " ")
+    s = strings.ReplaceAll(s, " /", "/")
+    s = strings.ReplaceAll(s, "/ ", "/")
+
+    //
+    // Mixed numbers have a space between the whole part and the fraction.
+    //
+    if whole, frac, ok := strings.Cut(s, " "); ok {
+        w, ok := parseBigInt(whole)
+        if !ok {
+            return BigRational{}, syntaxErr
+        }
+        // The fraction part has no sign of its own; the whole part's sign
+        // applies to all of it, e.g. "-1 2/3" is -5/3.
+        if strings.HasPrefix(frac, "-") || strings.HasPrefix(frac, "+") {
+            return BigRational{}, syntaxErr
+        }
+        f, err := parseBigFraction(frac)
+        if err != nil {
+            return BigRational{}, syntaxErr
+        }
+        if strings.HasPrefix(whole, "-") {
+            f = f.Multiply(bigFromRational(fromInt(-1)))
+        }
+        return bigFromInt(w).Add(f), nil
+    }
+
+    if strings.Contains(s, "/") {
+        r, err := parseBigFraction(s)
+        if err == ErrZeroDenominator {
+            return BigRational{}, fmt.Errorf("parsing %q: %w", s, err)
+        } else if err != nil {
+            return BigRational{}, syntaxErr
+        }
+        return r, nil
+    }
+
+    r, ok := parseBigDecimal(s)
+    if !ok {
+        return BigRational{}, syntaxErr
+    }
+    return r, nil
+}
+
+//
+// Parses "n/d" where n and d are integers. The error is non-nil if s isn't
+// in that form, or d is 0.
+//
+func parseBigFraction(s string) (BigRational, error) {
+    num, denom, ok := strings.Cut(s, "/")
+    if !ok {
+        return BigRational{}, ErrSyntax
+    }
+    n, ok1 := parseBigInt(num)
+    d, ok2 := parseBigInt(denom)
+    if !ok1 || !ok2 {
+        return BigRational{}, ErrSyntax
+    }
+    return makeBigRational(n, d)
+}
+
+//
+// Parses a decimal number like "-12.34(56)", where the digits in brackets
+// repeat forever. The value of "i.f(r)" is
+//
+//    i + f/10^len(f) + r/(10^len(f) * (10^len(r) - 1))
+//
+// e.g. 0.1(6) is 0 + 1/10 + 6/(10 * 9) = 1/6.
+//
+func parseBigDecimal(s string) (BigRational, bool) {
+    negative := false
+    if strings.HasPrefix(s, "-") || strings.HasPrefix(s, "+") {
+        negative = s[0] == '-'
+        s = s[1:]
+    }
+
+    intPart, fracPart, _ := strings.Cut(s, ".")
+    repeat := ""
+    if open := strings.Index(fracPart, "("); open >= 0 {
+        if !strings.HasSuffix(fracPart, ")") {
+            return BigRational{}, false
+        }
+        repeat = fracPart[open+1 : len(fracPart)-1]
+        fracPart = fracPart[:open]
+        if repeat == "" {
+            return BigRational{}, false
+        }
+    }
+    if intPart == "" && fracPart == "" && repeat == "" {
+        return BigRational{}, false
+    }
+    if !allDigits(intPart) || !allDigits(fracPart) || !allDigits(repeat) {
+        return BigRational{}, false
+    }
+
+    result := bigFromInt(digitsValue(intPart))
+    scale := pow10(len(fracPart))
+    if fracPart != "" {
+        f, _ := makeBigRational(digitsValue(fracPart), scale)
+        result = result.Add(f)
+    }
+    if repeat != "" {
+        d := pow10(len(repeat))
+        d.Sub(d, big.NewInt(1))
+        d.Mul(d, scale)
+        r, _ := makeBigRational(digitsValue(repeat), d)
+        result = result.Add(r)
+    }
+    if negative {
+        result = result.Multiply(bigFromRational(fromInt(-1)))
+    }
+    return result, true
+}
+
+//
+// Parses an integer with an optional sign.
+//
+func parseBigInt(s string) (*big.Int, bool) {
+    digits := strings.TrimLeft(s, "+-")
+    if len(s)-len(digits) > 1 || digits == "" || !allDigits(digits) {
+        return nil, false
+    }
+    return new(big.Int).SetString(s, 10)
+}
+
+//
+// Returns true if every character in s is one of 0-9. The empty string
+// counts as all digits.
+//
+func allDigits(s string) bool {
+    for _, c := range s {
+        if c < '0' || c > '9' {
+            return false
+        }
+    }
+    return true
+}
+
+//
+// Returns the value of the digits in s. The empty string has value 0.
+//
+func digitsValue(s string) *big.Int {
+    n, ok := new(big.Int).SetString(s, 10)
+    if !ok {
+        return big.NewInt(0)
+    }
+    return n
+}
+
+//
+// Returns 10^n.
+//
+func pow10(n int) *big.Int {
+    return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
+
+//
+// Returns the BigRational n/1.
+//
+func bigFromInt(n *big.Int) BigRational {
+    return BigRational{new(big.Int).Set(n), big.NewInt(1)}
+}
+
+//
+// Returns r as a mixed number, e.g. 5/3 is "1 2/3", -5/3 is "-1 2/3", 2/3
+// is "2/3", and 6/3 is "2". This is the reverse of parsing a mixed number.
+//
+func (r Rational) toMixed() string {
+    b := bigFromRational(r)
+    whole, rem := new(big.Int).QuoRem(b.num, b.denom, new(big.Int))
+    rem.Abs(rem)
+    switch {
+    case rem.Sign() == 0:
+        return whole.String()
+    case whole.Sign() == 0 && b.num.Sign() < 0:
+        return "-" + rem.String() + "/" + b.denom.String()
+    case whole.Sign() == 0:
+        return rem.String() + "/" + b.denom.String()
+    default:
+        return whole.String() + " " + rem.String() + "/" + b.denom.String()
+    }
+}
+
+//
+// Returns r as a decimal, with any repeating digits in ()-brackets, e.g.
+// 1/4 is "0.25", 1/6 is "0.1(6)", and 22/7 is "3.(142857)". The result can be
+// read back in with parseRational.
+//
+// The repeating part of 1/d can be up to d-1 digits long, so at most
+// maxDigits digits after the decimal point are generated. If the decimal is
+// cut short, then it ends with "...". If maxDigits is 0 or less, no digits
+// after the decimal point are generated at all, so 1/3 is "0...", rather
+// than the digits going on without a limit.
+//
+func (r Rational) toDecimal(maxDigits int) string {
+    return bigFromRational(r).toDecimal(maxDigits)
+}
+
+//
+// The digits after the decimal point are generated by long division. The
+// digits must start repeating as soon as a remainder comes up that's been
+// seen before, so the index of each remainder is saved in a map.
+//
+func (r BigRational) toDecimal(maxDigits int) string {
+    var sb strings.Builder
+    if r.num.Sign() < 0 {
+        sb.WriteString("-")
+    }
+    whole, rem := new(big.Int).QuoRem(new(big.Int).Abs(r.num), r.denom, new(big.Int))
+    sb.WriteString(whole.String())
+    if rem.Sign() == 0 {
+        return sb.String()
+    }
+
+    if maxDigits <= 0 {
+        return sb.String() + "..."
+    }
+
+    ten := big.NewInt(10)
+    var digits []byte
+    seen := map[string]int{} // remainder -> index in digits
+    for rem.Sign() != 0 {
+        if start, ok := seen[rem.String()]; ok {
+            return sb.String() + "." + string(digits[:start]) +
+                   "(" + string(digits[start:]) + ")"
+        }
+        if len(digits) == maxDigits {
+            return sb.String() + "." + string(digits) + "..."
+        }
+        seen[rem.String()] = len(digits)
+        rem.Mul(rem, ten)
+        d := new(big.Int)
+        d.QuoRem(rem, r.denom, rem)
+        digits = append(digits, byte('0'+d.Int64()))
+    }
+    return sb.String() + "." + string(digits)
+}
+
+//
+// Returns the continued fraction terms of r, e.g. 415/93 is
+//
+//    4 + 1/(2 + 1/(6 + 1/7))
+//
+// and so its terms are [4, 2, 6, 7]. The first term is floor(r), and may be
+// 0 or negative; the rest are positive.
+//
+// These are the quotients from Euclid's algorithm, and the remainders always
+// get smaller, so this can't overflow.
+//
+func (r Rational) toContinuedFraction() []int {
+    n, d := positiveDenom(r.Split())
+    var terms []int
+    for d != 0 {
+        q := n / d
+        if n%d != 0 && n < 0 {
+            q-- // round down, not towards 0
+        }
+        terms = append(terms, q)
+        n, d = d, n-q*d
+    }
+    return terms
+}
+
+//
+// Returns the rational with the given continued fraction terms, e.g. [4, 2,
+// 6, 7] gives 415/93. The terms are combined using the standard recurrence
+// for convergents:
+//
+//    h[i] = a[i]*h[i-1] + h[i-2]
+//    k[i] = a[i]*k[i-1] + k[i-2]
+//
+// which avoids doing any division. The error is non-nil if terms is empty,
+// the result overflows, or the terms make a fraction with denominator 0
+// (e.g. [1, 0]).
+//
+func fromContinuedFraction(terms []int) (Rational, error) {
+    if len(terms) == 0 {
+        return Rational{}, ErrNoTerms
+    }
+    h1, h2 := 1, 0 // h[i-1], h[i-2]
+    k1, k2 := 0, 1 // k[i-1], k[i-2]
+    for _, a := range terms {
+        h, err := mulAddInt(a, h1, h2)
+        if err != nil {
+            return Rational{}, err
+        }
+        k, err := mulAddInt(a, k1, k2)
+        if err != nil {
+            return Rational{}, err
+        }
+        h1, h2 = h, h1
+        k1, k2 = k, k1
+    }
+    return reduceChecked(h1, k1)
+}
+
+//
+// Returns a*b + c, or ErrOverflow.
+//
+func mulAddInt(a, b, c int) (int, error) {
+    ab, err := mulInt(a, b)
+    if err != nil {
+        return 0, err
+    }
+    return addInt(ab, c)
+}
+
+//
+// Returns the rational with denominator at most maxDenom that is closest to
+// x, e.g. bestApproximation(math.Pi, 1000) is 355/113.
+//
+// A float64 is exactly equal to some rational (with a power of 2
+// denominator). The continued fraction of that rational is expanded until
+// the next convergent's denominator would be too big. The answer is then
+// either the last convergent, or a "semiconvergent" between it and the
+// previous one, whichever is closer to x.
+//
+func bestApproximation(x float64, maxDenom int) (Rational, error) {
+    if math.IsNaN(x) || math.IsInf(x, 0) {
+        return Rational{}, ErrNotFinite
+    }
+    if maxDenom < 1 {
+        return Rational{}, fmt.Errorf("bestApproximation: maxDenom %v < 1: %w",
+            maxDenom, ErrZeroDenominator)
+    }
+    exact := bigFromFloat64(x)
+    limit := big.NewInt(int64(maxDenom))
+
+    // p0/q0 and p1/q1 are the last two convergents.
+    p0, q0 := big.NewInt(0), big.NewInt(1)
+    p1, q1 := big.NewInt(1), big.NewInt(0)
+    n, d := new(big.Int).Set(exact.num), new(big.Int).Set(exact.denom)
+    for d.Sign() != 0 {
+        a := new(big.Int)
+        m := new(big.Int)
+        a.DivMod(n, d, m) // floor division, since d > 0
+        q2 := new(big.Int).Mul(a, q1)
+        q2.Add(q2, q0)
+        if q2.Cmp(limit) > 0 {
+            break
+        }
+        p2 := new(big.Int).Mul(a, p1)
+        p2.Add(p2, p0)
+        p0, q0, p1, q1 = p1, q1, p2, q2
+        n, d = d, m
+    }
+
+    best := bigReduce(new(big.Int).Set(p1), new(big.Int).Set(q1))
+    if d.Sign() != 0 {
+        // The semiconvergent with the largest allowed denominator.
+        k := new(big.Int).Sub(limit, q0)
+        k.Quo(k, q1)
+        p := new(big.Int).Mul(k, p1)
+        p.Add(p, p0)
+        q := new(big.Int).Mul(k, q1)
+        q.Add(q, q0)
+        semi := bigReduce(p, q)
+        if bigDistance(semi, exact).Cmp(bigDistance(best, exact)) < 0 {
+            best = semi
+        }
+    }
+    return best.toRational()
+}
+
+//
+// Returns the BigRational exactly equal to x, which must be finite.
+// math.Frexp splits x into mant * 2^exp with 0.5 <= |mant| < 1, and mant has
+// at most 53 significant bits, so mant * 2^53 is an integer.
+//
+func bigFromFloat64(x float64) BigRational {
+    mant, exp := math.Frexp(x)
+    n := big.NewInt(int64(mant * (1 << 53)))
+    exp -= 53
+    d := big.NewInt(1)
+    if exp > 0 {
+        n.Lsh(n, uint(exp))
+    } else {
+        d.Lsh(d, uint(-exp))
+    }
+    return bigReduce(n, d)
+}
+
+//
+// Returns |a - b|.
+//
+func bigDistance(a, b BigRational) BigRational {
+    diff := a.Add(b.Multiply(bigFromRational(fromInt(-1))))
+    diff.num.Abs(diff.num)
+    return diff
+}
@@ -0,0 +1,200 @@
+// parse_test.go
+
+//
+// Tests for parsing, formatting and continued fractions.
+//
+
+package main
+
+import (
+    "errors"
+    "math"
+    "slices"
+    "testing"
+)
+
+func TestParseRational(t *testing.T) {
+    tests := []struct {
+        s       string
+        want    Rational
+        wantErr error
+    }{
+        {"5/3", Rational{5, 3}, nil},
+        {" 10/6 ", Rational{5, 3}, nil},
+        {"-5/3", Rational{-5, 3}, nil},
+        {"5/-3", Rational{-5, 3}, nil},
+        {"5 / 3", Rational{5, 3}, nil},
+        {"  1  2 / 3 ", Rational{5, 3}, nil},
+        {"7", Rational{7, 1}, nil},
+        {"-7", Rational{-7, 1}, nil},
+        {"1 2/3", Rational{5, 3}, nil},
+        {"-1 2/3", Rational{-5, 3}, nil},
+        {"2 4/2", Rational{4, 1}, nil},
+        {"2.5", Rational{5, 2}, nil},
+        {"-.25", Rational{-1, 4}, nil},
+        {"3.", Rational{3, 1}, nil},
+        {"0.1(6)", Rational{1, 6}, nil},
+        {"0.(3)", Rational{1, 3}, nil},
+        {"-1.(142857)", Rational{-8, 7}, nil},
+        {"0.(9)", Rational{1, 1}, nil},
+        {"1/0", Rational{}, ErrZeroDenominator},
+        {"", Rational{}, ErrSyntax},
+        {"abc", Rational{}, ErrSyntax},
+        {"1/2/3", Rational{}, ErrSyntax},
+        {"--1", Rational{}, ErrSyntax},
+        {"1 -2/3", Rational{}, ErrSyntax},
+        {"1 2", Rational{}, ErrSyntax},
+        {"0.1(6", Rational{}, ErrSyntax},
+        {"0.1()", Rational{}, ErrSyntax},
+        {"0.(6)1", Rational{}, ErrSyntax},
+        {".", Rational{}, ErrSyntax},
+        {"99999999999999999999/1", Rational{}, ErrOverflow},
+    }
+    for _, tc := range tests {
+        got, err := parseRational(tc.s)
+        if tc.wantErr != nil {
+            if !errors.Is(err, tc.wantErr) {
+                t.Errorf("parseRational(%q) = %v, %v, expected error %v", tc.s, got, err, tc.wantErr)
+            }
+        } else if err != nil || got != tc.want {
+            t.Errorf("parseRational(%q) = %v, %v, expected %v", tc.s, got, err, tc.want)
+        }
+    }
+}
+
+func TestFormat(t *testing.T) {
+    tests := []struct {
+        r       Rational
+        mixed   string
+        decimal string
+    }{
+        {Rational{5, 3}, "1 2/3", "1.(6)"},
+        {Rational{-5, 3}, "-1 2/3", "-1.(6)"},
+        {Rational{2, -3}, "-2/3", "-0.(6)"},
+        {Rational{6, 3}, "2", "2"},
+        {Rational{1, 4}, "1/4", "0.25"},
+        {Rational{1, 6}, "1/6", "0.1(6)"},
+        {Rational{22, 7}, "3 1/7", "3.(142857)"},
+        {Rational{1, 12}, "1/12", "0.08(3)"},
+        {Rational{0, 5}, "0", "0"},
+    }
+    for _, tc := range tests {
+        if got := tc.r.toMixed(); got != tc.mixed {
+            t.Errorf("%v.toMixed() = %q, expected %q", tc.r, got, tc.mixed)
+        }
+        if got := tc.r.toDecimal(100); got != tc.decimal {
+            t.Errorf("%v.toDecimal(100) = %q, expected %q", tc.r, got, tc.decimal)
+        }
+
+        // Both forms must parse back to the same value.
+        if m, err := parseRational(tc.mixed); err != nil || !m.Equal(tc.r) {
+            t.Errorf("parseRational(%q) = %v, %v, expected %v", tc.mixed, m, err, tc.r)
+        }
+        if d, err := parseRational(tc.decimal); err != nil || !d.Equal(tc.r) {
+            t.Errorf("parseRational(%q) = %v, %v, expected %v", tc.decimal, d, err, tc.r)
+        }
+    }
+
+    //
+    // Decimals cut short after maxDigits digits. With no digits allowed,
+    // only whole numbers come out exactly.
+    //
+    truncated := []struct {
+        r         Rational
+        maxDigits int
+        expected  string
+    }{
+        {Rational{1, 97}, 5, "0.01030..."},
+        {Rational{1, 7}, 3, "0.142..."},
+        {Rational{1, 3}, 0, "0..."},
+        {Rational{-7, 3}, -1, "-2..."},
+        {Rational{6, 3}, 0, "2"},
+        {Rational{1, 4}, 2, "0.25"},
+    }
+    for _, tc := range truncated {
+        if got := tc.r.toDecimal(tc.maxDigits); got != tc.expected {
+            t.Errorf("%v.toDecimal(%v) = %q, expected %q", tc.r, tc.maxDigits, got, tc.expected)
+        }
+    }
+}
+
+func TestContinuedFraction(t *testing.T) {
+    tests := []struct {
+        r     Rational
+        terms []int
+    }{
+        {Rational{415, 93}, []int{4, 2, 6, 7}},
+        {Rational{3, 1}, []int{3}},
+        {Rational{1, 3}, []int{0, 3}},
+        {Rational{-7, 3}, []int{-3, 1, 2}},
+        {Rational{7, -3}, []int{-3, 1, 2}},
+        {Rational{0, 1}, []int{0}},
+        {Rational{355, 113}, []int{3, 7, 16}},
+    }
+    for _, tc := range tests {
+        terms := tc.r.toContinuedFraction()
+        if !slices.Equal(terms, tc.terms) {
+            t.Errorf("%v.toContinuedFraction() = %v, expected %v", tc.r, terms, tc.terms)
+        }
+        if back, err := fromContinuedFraction(terms); err != nil || !back.Equal(tc.r) {
+            t.Errorf("fromContinuedFraction(%v) = %v, %v, expected %v", terms, back, err, tc.r)
+        }
+    }
+
+    errTests := []struct {
+        terms   []int
+        wantErr error
+    }{
+        {nil, ErrNoTerms},
+        {[]int{1, 0}, ErrZeroDenominator},
+        {[]int{math.MaxInt, 2}, ErrOverflow},
+    }
+    for _, tc := range errTests {
+        if _, err := fromContinuedFraction(tc.terms); err != tc.wantErr {
+            t.Errorf("fromContinuedFraction(%v) error = %v, expected %v", tc.terms, err, tc.wantErr)
+        }
+    }
+}
+
+func TestBestApproximation(t *testing.T) {
+    tests := []struct {
+        x        float64
+        maxDenom int
+        want     Rational
+    }{
+        {math.Pi, 1, Rational{3, 1}},
+        {math.Pi, 10, Rational{22, 7}},
+        {math.Pi, 100, Rational{311, 99}},
+        {math.Pi, 1000, Rational{355, 113}},
+        {-math.Pi, 1000, Rational{-355, 113}},
+        {0.5, 1000, Rational{1, 2}},
+        {0.1, 1000000, Rational{1, 10}},
+        {1.0 / 3.0, 100, Rational{1, 3}},
+        {math.Sqrt2, 100, Rational{140, 99}},
+        {0, 10, Rational{0, 1}},
+        {2.75, 3, Rational{8, 3}},
+    }
+    for _, tc := range tests {
+        if got, err := bestApproximation(tc.x, tc.maxDenom); err != nil || got != tc.want {
+            t.Errorf("bestApproximation(%v, %v) = %v, %v, expected %v",
+                     tc.x, tc.maxDenom, got, err, tc.want)
+        }
+    }
+
+    errTests := []struct {
+        x        float64
+        maxDenom int
+        wantErr  error
+    }{
+        {math.NaN(), 10, ErrNotFinite},
+        {math.Inf(1), 10, ErrNotFinite},
+        {1.5, 0, ErrZeroDenominator},
+        {1e300, 10, ErrOverflow},
+    }
+    for _, tc := range errTests {
+        if _, err := bestApproximation(tc.x, tc.maxDenom); !errors.Is(err, tc.wantErr) {
+            t.Errorf("bestApproximation(%v, %v) error = %v, expected %v",
+                     tc.x, tc.maxDenom, err, tc.wantErr)
+        }
+    }
+}
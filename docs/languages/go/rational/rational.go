@@ -0,0 +1,306 @@
+// rational.go
+
+//
+// A rational number type for the rationals assignment (see
+// ../../../assignments/rationals.md). The number of each operation from the
+// assignment is given in a comment near its implementation.
+//
+// This directory is one program made of several files, plus tests in the
+// _test.go files, so run it and test it like this:
+//
+//    $ go run $(ls *.go | grep -v _test.go)
+//    $ go test *.go
+//
+
+package main
+
+import (
+    "errors"
+    "fmt"
+    "math"
+)
+
+type Floater64 interface {
+    // Converts a value to an equivalent float64.
+    toFloat64() float64
+}
+
+type Rationalizer interface {
+
+    // 5. Rationalizers implement the standard Stringer interface.
+    fmt.Stringer
+
+    // 6. Rationalizers implement the Floater64 interface.
+    Floater64
+
+    // 2. Returns the numerator.
+    Numerator() int
+
+    // 3. Returns the denominator.
+    Denominator() int
+
+    // 4. Returns the numerator, denominator.
+    Split() (int, int)
+
+    // 7. Returns true iff this value equals other.
+    Equal(other Rationalizer) bool
+
+    // 8. Returns true iff this value is less than other.
+    LessThan(other Rationalizer) bool
+
+    // 9. Returns true iff the value equal an integer.
+    IsInt() bool
+
+    // 10. Returns the sum of this value with other.
+    Add(other Rationalizer) Rationalizer
+
+    // 11. Returns the product of this value with other.
+    Multiply(other Rationalizer) Rationalizer
+
+    // 12. Returns the quotient of this value with other. The error is nil
+    // if its is successful, and a non-nil if it cannot be divided.
+    Divide(other Rationalizer) (Rationalizer, error)
+
+    // 13. Returns the reciprocal. The error is nil if it is successful,
+    // and non-nil if it cannot be inverted.
+    Invert() (Rationalizer, error)
+
+    // 14. Returns an equal value in lowest terms.
+    ToLowestTerms() Rationalizer
+} // Rationalizer interface
+
+//
+// Use makeRational to create a Rational, since it checks that the
+// denominator isn't 0. Writing Rational{1, 0} directly skips that check.
+//
+type Rational struct {
+    num, denom int
+}
+
+//
+// Errors are ordinary values in Go. Declaring them once lets callers test
+// for a particular error with ==.
+//
+var (
+    ErrZeroDenominator = errors.New("rational: denominator is 0")
+    ErrDivideByZero    = errors.New("rational: division by 0")
+    ErrNoInverse       = errors.New("rational: 0 has no inverse")
+    ErrBadHarmonic     = errors.New("rational: harmonic sum needs n > 0")
+)
+
+//
+// 1. Returns a new rational n/d. If d is 0, then the returned error is
+// non-nil and the returned Rational should not be used.
+//
+// The rational is *not* reduced to lowest terms, e.g. makeRational(2, 4)
+// has numerator 2 and denominator 4. Use ToLowestTerms to reduce it.
+//
+func makeRational(n, d int) (Rational, error) {
+    if d == 0 {
+        return Rational{}, ErrZeroDenominator
+    }
+    return Rational{n, d}, nil
+}
+
+//
+// Returns the rational n/1. This can't fail, so there's no error.
+//
+func fromInt(n int) Rational {
+    return Rational{n, 1}
+}
+
+//
+// 2. Returns the numerator.
+//
+func (r Rational) Numerator() int {
+    return r.num
+}
+
+//
+// 3. Returns the denominator.
+//
+func (r Rational) Denominator() int {
+    return r.denom
+}
+
+//
+// 4. Returns the numerator and denominator.
+//
+func (r Rational) Split() (int, int) {
+    return r.num, r.denom
+}
+
+//
+// 5. Returns r as a string like "5/3".
+//
+func (r Rational) String() string {
+    return fmt.Sprintf("%v/%v", r.num, r.denom)
+}
+
+//
+// 6. Returns r as a float64, e.g. 5/2 is 2.5.
+//
+func (r Rational) toFloat64() float64 {
+    return float64(r.num) / float64(r.denom)
+}
+
+//
+// 7. Returns true if r and other are equal. They don't need to be in lowest
+// terms: a/b == c/d exactly when a*d == b*c.
+//
+func (r Rational) Equal(other Rationalizer) bool {
+    c, d := other.Split()
+    return crossCompare(r.num, r.denom, c, d) == 0
+}
+
+//
+// 8. Returns true if r is less than other. The cross-multiplication trick
+// only works when both denominators are positive, so compare flips the
+// result when exactly one of them is negative.
+//
+func (r Rational) LessThan(other Rationalizer) bool {
+    return compare(r, other) < 0
+}
+
+//
+// Returns -1 if a < b, 0 if a == b, and 1 if a > b. crossCompare (in
+// overflow.go) does the multiplying, so this works even for big values.
+//
+func compare(a, b Rationalizer) int {
+    an, ad := a.Split()
+    bn, bd := b.Split()
+    result := crossCompare(an, ad, bn, bd)
+    // Multiplying both sides by a negative denominator flips the comparison.
+    if (ad < 0) != (bd < 0) {
+        result = -result
+    }
+    return result
+}
+
+//
+// 9. Returns true if r equals an integer, e.g. 4/1, 21/3 and 0/99.
+//
+func (r Rational) IsInt() bool {
+    return r.num%r.denom == 0
+}
+
+//
+// 10. Returns r + other, in lowest terms.
+//
+func (r Rational) Add(other Rationalizer) Rationalizer {
+    c, d := other.Split()
+    return reduce(r.num*d+r.denom*c, r.denom*d)
+}
+
+//
+// 11. Returns r * other, in lowest terms.
+//
+func (r Rational) Multiply(other Rationalizer) Rationalizer {
+    c, d := other.Split()
+    return reduce(r.num*c, r.denom*d)
+}
+
+//
+// 12. Returns r / other, in lowest terms. Dividing by a rational equal to 0
+// returns a non-nil error.
+//
+func (r Rational) Divide(other Rationalizer) (Rationalizer, error) {
+    c, d := other.Split()
+    if c == 0 {
+        return nil, ErrDivideByZero
+    }
+    return reduce(r.num*d, r.denom*c), nil
+}
+
+//
+// 13. Returns 1/r. A rational equal to 0 has no inverse, and so in that case
+// a non-nil error is returned.
+//
+func (r Rational) Invert() (Rationalizer, error) {
+    if r.num == 0 {
+        return nil, ErrNoInverse
+    }
+    return Rational{r.denom, r.num}, nil
+}
+
+//
+// 14. Returns r in lowest terms with a positive denominator, e.g. 36/20
+// becomes 9/5, 3/-6 becomes -1/2, and 0/-7 becomes 0/1.
+//
+func (r Rational) ToLowestTerms() Rationalizer {
+    return reduce(r.num, r.denom)
+}
+
+//
+// Returns n/d in lowest terms with a positive denominator. d must not be 0.
+//
+// Dividing by the gcd comes before flipping the signs, because -math.MinInt
+// doesn't fit in an int. The one case that still can't be done is when d is
+// math.MinInt and n is odd: the denominator would have to be 2^63, which
+// doesn't fit either, so it's left negative. reduceChecked returns
+// ErrOverflow for it instead.
+//
+func reduce(n, d int) Rational {
+    g := gcd(n, d)
+    n, d = n/g, d/g
+    if d == math.MinInt {
+        return Rational{n, d}
+    }
+    n, d = positiveDenom(n, d)
+    return Rational{n, d}
+}
+
+//
+// Returns n, d with their signs flipped if d is negative, so that n/d is
+// unchanged and the denominator is positive.
+//
+func positiveDenom(n, d int) (int, int) {
+    if d < 0 {
+        return -n, -d
+    }
+    return n, d
+}
+
+//
+// Returns the greatest common divisor of a and b using Euclid's algorithm.
+// The result is always positive, except that gcd(0, 0) is 0. Since reduce
+// is never called with d == 0, it never divides by 0.
+//
+// -math.MinInt is math.MinInt again, so the absolute values are taken as
+// uints, which have room for 2^63. The only gcd that doesn't fit back into
+// an int is 2^63 itself, when a and b are both math.MinInt or 0. Then the
+// result is math.MinInt, which divides a and b just as well.
+//
+func gcd(a, b int) int {
+    x, y := abs(a), abs(b)
+    for y != 0 {
+        x, y = y, x%y
+    }
+    return int(x)
+}
+
+//
+// Returns the absolute value of n as a uint, which is correct even for
+// math.MinInt.
+//
+func abs(n int) uint {
+    if n < 0 {
+        return -uint(n)
+    }
+    return uint(n)
+}
+
+//
+// 15. Returns the harmonic sum 1/1 + 1/2 + ... + 1/n. n must be greater than
+// 0.
+//
+func harmonicSum(n int) (Rational, error) {
+    if n <= 0 {
+        return Rational{}, ErrBadHarmonic
+    }
+    result := fromInt(0)
+    for i := 1; i <= n; i++ {
+        result = result.Add(Rational{1, i}).(Rational)
+    }
+    return result, nil
+}
@@ -0,0 +1,259 @@
+// rational_test.go
+
+//
+// Tests for the Rational type. Each table is a list of test cases, and a
+// single loop runs all the cases in the table. Run all the tests in this
+// directory like this:
+//
+//    $ go test *.go
+//
+
+package main
+
+import (
+    "math"
+    "testing"
+)
+
+//
+// Makes a rational from a known-good numerator and denominator.
+//
+func mustRational(n, d int) Rational {
+    r, err := makeRational(n, d)
+    if err != nil {
+        panic(err)
+    }
+    return r
+}
+
+//
+// 1-5: making rationals and getting at their parts
+//
+func TestMakeRational(t *testing.T) {
+    tests := []struct {
+        n, d    int
+        wantErr error
+        str     string
+    }{
+        {1, 2, nil, "1/2"},
+        {2, 4, nil, "2/4"},
+        {-3, 7, nil, "-3/7"},
+        {3, -7, nil, "3/-7"},
+        {0, 5, nil, "0/5"},
+        {4, 0, ErrZeroDenominator, ""},
+        {0, 0, ErrZeroDenominator, ""},
+    }
+    for _, tc := range tests {
+        r, err := makeRational(tc.n, tc.d)
+        if tc.wantErr != nil {
+            if err != tc.wantErr {
+                t.Errorf("makeRational(%v, %v) error = %v, expected %v", tc.n, tc.d, err, tc.wantErr)
+            }
+            continue
+        }
+        if err != nil {
+            t.Errorf("makeRational(%v, %v) error = %v", tc.n, tc.d, err)
+            continue
+        }
+        n, d := r.Split()
+        if r.Numerator() != tc.n || r.Denominator() != tc.d || n != tc.n || d != tc.d {
+            t.Errorf("makeRational(%v, %v) = %v/%v, split into %v, %v",
+                     tc.n, tc.d, r.Numerator(), r.Denominator(), n, d)
+        }
+        if r.String() != tc.str {
+            t.Errorf("makeRational(%v, %v).String() = %q, expected %q", tc.n, tc.d, r.String(), tc.str)
+        }
+    }
+}
+
+//
+// 6: converting to a float64
+//
+func TestToFloat64(t *testing.T) {
+    tests := []struct {
+        r    Rational
+        want float64
+    }{
+        {mustRational(5, 2), 2.5},
+        {mustRational(-1, 4), -0.25},
+        {mustRational(1, -4), -0.25},
+        {mustRational(0, 9), 0.0},
+    }
+    for _, tc := range tests {
+        if got := tc.r.toFloat64(); got != tc.want {
+            t.Errorf("%v.toFloat64() = %v, expected %v", tc.r, got, tc.want)
+        }
+    }
+}
+
+//
+// 7-9: comparisons
+//
+func TestCompare(t *testing.T) {
+    tests := []struct {
+        a, b        Rational
+        equal, less bool
+    }{
+        {mustRational(1, 2), mustRational(2, 4), true, false},
+        {mustRational(1, 2), mustRational(-1, -2), true, false},
+        {mustRational(1, 3), mustRational(1, 2), false, true},
+        {mustRational(1, 2), mustRational(1, 3), false, false},
+        {mustRational(-1, 2), mustRational(1, 3), false, true},
+        {mustRational(1, -2), mustRational(1, 3), false, true},
+        {mustRational(1, 3), mustRational(-1, -2), false, true},
+        {mustRational(0, 5), mustRational(0, -3), true, false},
+        {mustRational(-2, 3), mustRational(-1, 3), false, true},
+    }
+    for _, tc := range tests {
+        if got := tc.a.Equal(tc.b); got != tc.equal {
+            t.Errorf("%v.Equal(%v) = %v, expected %v", tc.a, tc.b, got, tc.equal)
+        }
+        if got := tc.a.LessThan(tc.b); got != tc.less {
+            t.Errorf("%v.LessThan(%v) = %v, expected %v", tc.a, tc.b, got, tc.less)
+        }
+    }
+}
+
+func TestIsInt(t *testing.T) {
+    tests := []struct {
+        r    Rational
+        want bool
+    }{
+        {mustRational(4, 1), true},
+        {mustRational(21, 3), true},
+        {mustRational(0, 99), true},
+        {mustRational(-8, 4), true},
+        {mustRational(5, 3), false},
+        {mustRational(-1, 2), false},
+    }
+    for _, tc := range tests {
+        if got := tc.r.IsInt(); got != tc.want {
+            t.Errorf("%v.IsInt() = %v, expected %v", tc.r, got, tc.want)
+        }
+    }
+}
+
+//
+// 10-12: arithmetic. Results are compared field by field, so they must be in
+// lowest terms with a positive denominator.
+//
+func TestArithmetic(t *testing.T) {
+    tests := []struct {
+        a, b                Rational
+        sum, product, quot  Rational
+    }{
+        {mustRational(1, 2), mustRational(1, 3), mustRational(5, 6), mustRational(1, 6), mustRational(3, 2)},
+        {mustRational(1, 2), mustRational(1, 2), mustRational(1, 1), mustRational(1, 4), mustRational(1, 1)},
+        {mustRational(2, 3), mustRational(-2, 3), mustRational(0, 1), mustRational(-4, 9), mustRational(-1, 1)},
+        {mustRational(3, -4), mustRational(1, 4), mustRational(-1, 2), mustRational(-3, 16), mustRational(-3, 1)},
+        {mustRational(-1, -2), mustRational(6, 4), mustRational(2, 1), mustRational(3, 4), mustRational(1, 3)},
+    }
+    for _, tc := range tests {
+        if sum := tc.a.Add(tc.b); sum != tc.sum {
+            t.Errorf("%v + %v = %v, expected %v", tc.a, tc.b, sum, tc.sum)
+        }
+        if product := tc.a.Multiply(tc.b); product != tc.product {
+            t.Errorf("%v * %v = %v, expected %v", tc.a, tc.b, product, tc.product)
+        }
+        if quot, err := tc.a.Divide(tc.b); err != nil || quot != tc.quot {
+            t.Errorf("%v / %v = %v, %v, expected %v", tc.a, tc.b, quot, err, tc.quot)
+        }
+    }
+
+    if _, err := mustRational(1, 2).Divide(mustRational(0, 3)); err != ErrDivideByZero {
+        t.Errorf("1/2 / 0/3 error = %v, expected %v", err, ErrDivideByZero)
+    }
+}
+
+//
+// 14: reducing to lowest terms
+//
+func TestToLowestTerms(t *testing.T) {
+    tests := []struct {
+        r, want Rational
+    }{
+        {mustRational(36, 20), mustRational(9, 5)},
+        {mustRational(-36, 20), mustRational(-9, 5)},
+        {mustRational(36, -20), mustRational(-9, 5)},
+        {mustRational(-36, -20), mustRational(9, 5)},
+        {mustRational(0, -7), mustRational(0, 1)},
+        {mustRational(7, 7), mustRational(1, 1)},
+
+        // -math.MinInt doesn't fit in an int, so these need care.
+        {mustRational(math.MinInt, 6), mustRational(math.MinInt/2, 3)},
+        {mustRational(math.MinInt, -6), mustRational(-(math.MinInt / 2), 3)},
+        {mustRational(6, math.MinInt), mustRational(-3, -(math.MinInt / 2))},
+        {mustRational(math.MinInt, math.MinInt), mustRational(1, 1)},
+        {mustRational(math.MinInt, 1), mustRational(math.MinInt, 1)},
+        {mustRational(0, math.MinInt), mustRational(0, 1)},
+        {mustRational(math.MaxInt, math.MinInt), mustRational(math.MaxInt, math.MinInt)},
+    }
+    for _, tc := range tests {
+        if got := tc.r.ToLowestTerms(); got != tc.want {
+            t.Errorf("%v.ToLowestTerms() = %v, expected %v", tc.r, got, tc.want)
+        }
+    }
+}
+
+func TestGcd(t *testing.T) {
+    tests := []struct {
+        a, b, want int
+    }{
+        {36, 20, 4},
+        {-36, 20, 4},
+        {36, -20, 4},
+        {7, 0, 7},
+        {0, -7, 7},
+        {0, 0, 0},
+        {math.MinInt, 6, 2},
+        {6, math.MinInt, 2},
+        {math.MinInt, math.MaxInt, 1},
+        {math.MinInt, 1 << 62, 1 << 62},
+        {math.MinInt, math.MinInt, math.MinInt}, // 2^63 doesn't fit
+    }
+    for _, tc := range tests {
+        if got := gcd(tc.a, tc.b); got != tc.want {
+            t.Errorf("gcd(%v, %v) = %v, expected %v", tc.a, tc.b, got, tc.want)
+        }
+    }
+}
+
+//
+// 13: inverting
+//
+func TestInvert(t *testing.T) {
+    if inv, err := mustRational(2, 3).Invert(); err != nil || inv != mustRational(3, 2) {
+        t.Errorf("2/3.Invert() = %v, %v", inv, err)
+    }
+    if inv, err := mustRational(-2, 3).Invert(); err != nil || !inv.Equal(mustRational(-3, 2)) {
+        t.Errorf("-2/3.Invert() = %v, %v", inv, err)
+    }
+    if _, err := mustRational(0, 3).Invert(); err != ErrNoInverse {
+        t.Errorf("0/3.Invert() error = %v, expected %v", err, ErrNoInverse)
+    }
+}
+
+//
+// 15: harmonic sums
+//
+func TestHarmonicSum(t *testing.T) {
+    tests := []struct {
+        n    int
+        want Rational
+    }{
+        {1, mustRational(1, 1)},
+        {2, mustRational(3, 2)},
+        {3, mustRational(11, 6)},
+        {4, mustRational(25, 12)},
+        {10, mustRational(7381, 2520)},
+    }
+    for _, tc := range tests {
+        if h, err := harmonicSum(tc.n); err != nil || h != tc.want {
+            t.Errorf("harmonicSum(%v) = %v, %v, expected %v", tc.n, h, err, tc.want)
+        }
+    }
+    if _, err := harmonicSum(0); err != ErrBadHarmonic {
+        t.Errorf("harmonicSum(0) error = %v, expected %v", err, ErrBadHarmonic)
+    }
+}
+
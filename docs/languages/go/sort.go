@@ -1,9 +1,30 @@
 // sort.go
 
+//
+// Insertion, binary search and a sorted slice type, and a library of
+// sorting algorithms. The tests are in sort_test.go; since this folder has
+// many programs in it, run them by naming both files:
+//
+//    $ go test sort.go sort_test.go
+//
+// To fuzz test, i.e. run the tests on lots of randomly made inputs:
+//
+//    $ go test -fuzz=FuzzSort$ sort.go sort_test.go
+//
+
 package main
 
 import (
+    "cmp"
     "fmt"
+    "io"
+    "math/bits"
+    "os"
+    "runtime"
+    "slices"
+    "sort"
+    "strings"
+    "time"
 )
 
 //
@@ -11,12 +32,16 @@ import (
 // src) copies the contents of slice src into slice dest. It works correctly
 // if dest and src overlap.
 //
+// They are generic: T is a type parameter, and can be any type, so the same
+// function works for []int, []string, []Person, etc. Go works out T from the
+// arguments, e.g. in insert1("x", 0, []string{"y"}) T is string.
+//
 
 //
 // Returns a copy of lst with x inserted at location i e.g. insert(9, 2, {3,
 // 2, 1, 4}) returns {3, 2, 9, 1, 4}.
 //
-func insert1(x int, i int, lst []int) []int {
+func insert1[T any](x T, i int, lst []T) []T {
     n := len(lst)
     
     // Force i to be in the range 0 to n.
@@ -27,7 +52,7 @@ func insert1(x int, i int, lst []int) []int {
     }
     
     // result is a new slice of size n+1
-    result := make([]int, n+1)
+    result := make([]T, n+1)
 
     // Get the left and right slice of lst.
     left, right := lst[:i], lst[i:]
@@ -49,7 +74,7 @@ func insert1(x int, i int, lst []int) []int {
 //
 // Another way of writing insert.
 //
-func insert2(x int, i int, lst []int) []int {
+func insert2[T any](x T, i int, lst []T) []T {
     n := len(lst)
 
     // Force i to be in the range 0 to n.
@@ -60,10 +85,12 @@ func insert2(x int, i int, lst []int) []int {
     }
 
     // result is a copy of lst
-    result := append([]int{}, lst...)
+    result := append([]T{}, lst...)
 
-    // add 1 more int to lst, for the x to be inserted
-    result = append(result, 0)
+    // add 1 more element to lst, for the x to be inserted; zero is T's zero
+    // value, e.g. 0 for int and "" for string
+    var zero T
+    result = append(result, zero)
 
     // Shift each int in the right part of result one element to the right.
     copy(result[i+1:], result[i:])
@@ -75,9 +102,9 @@ func insert2(x int, i int, lst []int) []int {
 }
 
 //
-// Helper function used by testInsert.
+// Returns true if a and b have the same elements in the same order.
 //
-func testEq(a, b []int) bool {
+func testEq[T comparable](a, b []T) bool {
     if len(a) != len(b) {
         return false
     }
@@ -90,47 +117,120 @@ func testEq(a, b []int) bool {
 }
 
 //
-// Tests if an insert function works correctly.
+// A sortRecorder is told about each step that insertionSort,
+// insertionSortFunc, sequentialMergeSort and parallelMergeSort take, e.g. to
+// count them or draw them. It's an optional last argument to those sorts.
+// The arguments are indexes into the slice being sorted:
 //
-func testInsert(insert func(int, int, []int) []int) {
-    testNum := 0
-    pass, fail := 0, 0
-    check := func (x int, i int, lst []int, expected []int) {
-        testNum++
-        result := insert(x, i, lst)
-        fmt.Printf("test %v: insert(%v, %v, %v) = %v  ", 
-                           testNum,  x,  i,  lst,  result)
-        if testEq(result, expected) {
-            pass++
-            fmt.Println("passed")
-        } else {
-            fail++
-            fmt.Printf("test %v FAILED\n", testNum)
-        }
+// - compare(i, j): a[i] and a[j] were compared. In a merge, they're the
+//   indexes the two values had when the merge started.
+// - swap(i, j): a[i] and a[j] were swapped
+// - save(lo, hi): a[lo:hi] was copied to merge sort's temporary slice
+// - move(i, from): a[i] was set to the value that the last save copied
+//   from a[from]
+//
+// Any of them can be nil. parallelMergeSort calls them from several
+// goroutines at once (for different parts of a), so they must be safe for
+// that, e.g. by using a mutex.
+//
+type sortRecorder struct {
+    compare func(i, j int)
+    swap    func(i, j int)
+    save    func(lo, hi int)
+    move    func(i, from int)
+}
+
+//
+// Returns the recorder passed as the optional last argument of a sort, or
+// nil if there isn't one. The methods below do nothing if r is nil.
+//
+func recorderOf(rec []*sortRecorder) *sortRecorder {
+    if len(rec) == 0 {
+        return nil
     }
-    check(9, 0, []int{},          []int{9})
-    check(9, 0, []int{0},         []int{9,0})
-    check(9, 1, []int{0},         []int{0,9})
-    check(9, 0, []int{0,1,2,3,4}, []int{9,0,1,2,3,4})
-    check(9, 1, []int{0,1,2,3,4}, []int{0,9,1,2,3,4})
-    check(9, 2, []int{0,1,2,3,4}, []int{0,1,9,2,3,4})
-    check(9, 3, []int{0,1,2,3,4}, []int{0,1,2,9,3,4})
-    check(9, 4, []int{0,1,2,3,4}, []int{0,1,2,3,9,4})
-    check(9, 5, []int{0,1,2,3,4}, []int{0,1,2,3,4,9})
+    return rec[0]
+}
 
-    fmt.Printf("Done. %v/%v passed, %v/%v failed\n",
-        pass, testNum, fail, testNum)
-} // testInsert
+func (r *sortRecorder) compared(i, j int) {
+    if r != nil && r.compare != nil {
+        r.compare(i, j)
+    }
+}
+
+func (r *sortRecorder) swapped(i, j int) {
+    if r != nil && r.swap != nil {
+        r.swap(i, j)
+    }
+}
+
+func (r *sortRecorder) saved(lo, hi int) {
+    if r != nil && r.save != nil {
+        r.save(lo, hi)
+    }
+}
+
+func (r *sortRecorder) moved(i, from int) {
+    if r != nil && r.move != nil {
+        r.move(i, from)
+    }
+}
+
+//
+// Returns a recorder for sorting the part of a slice that starts at index
+// offset, which adds offset to the indexes before passing them on to r.
+//
+func (r *sortRecorder) shift(offset int) *sortRecorder {
+    if r == nil || offset == 0 {
+        return r
+    }
+    return &sortRecorder{
+        compare: func(i, j int) { r.compared(i+offset, j+offset) },
+        swap:    func(i, j int) { r.swapped(i+offset, j+offset) },
+        save:    func(lo, hi int) { r.saved(lo+offset, hi+offset) },
+        move:    func(i, from int) { r.moved(i+offset, from+offset) },
+    }
+}
 
 //
 // Sorts slice a into ascending sorted order using insertion sort.
 //
-func insertionSort(a []int) {
+// cmp.Ordered is a constraint: T can only be a type that the < and >
+// operators work on, i.e. numbers and strings.
+//
+// rec is an optional sortRecorder (see above); the ... means insertionSort
+// can be called with or without it.
+//
+func insertionSort[T cmp.Ordered](a []T, rec ...*sortRecorder) {
+    r := recorderOf(rec)
     n := len(a)
     if n < 2 { return }  // {}-braces always required in if-statements
     for i := 1; i < n; i++ {
-        for j := i; j > 0 && a[j-1] > a[j]; j-- {
+        for j := i; j > 0; j-- {
+            r.compared(j-1, j)
+            if !(a[j-1] > a[j]) {
+                break
+            }
             a[j], a[j-1] = a[j-1], a[j]  // swap a[j], a[j-1]
+            r.swapped(j-1, j)
+        }
+    }
+}
+
+//
+// Like insertionSort, but for any type of value: less(x, y) returns true if
+// x should come before y. Values for which less is false both ways stay in
+// the same order, i.e. the sort is stable.
+//
+func insertionSortFunc[T any](a []T, less func(x, y T) bool, rec ...*sortRecorder) {
+    r := recorderOf(rec)
+    for i := 1; i < len(a); i++ {
+        for j := i; j > 0; j-- {
+            r.compared(j, j-1)
+            if !less(a[j], a[j-1]) {
+                break
+            }
+            a[j], a[j-1] = a[j-1], a[j]
+            r.swapped(j-1, j)
         }
     }
 }
@@ -138,7 +238,7 @@ func insertionSort(a []int) {
 //
 // Returns true if a is in ascending sorted order, and false otherwise.
 //
-func isSorted(a []int) bool {
+func isSorted[T cmp.Ordered](a []T) bool {
     for i := 1; i < len(a); i++ {
         if a[i-1] > a[i] {
             return false
@@ -148,27 +248,1356 @@ func isSorted(a []int) bool {
 }
 
 //
-// Test if a sorting function is correct.
+// Returns true if a is sorted according to less, and false otherwise.
+//
+func isSortedFunc[T any](a []T, less func(x, y T) bool) bool {
+    for i := 1; i < len(a); i++ {
+        if less(a[i], a[i-1]) {
+            return false
+        }
+    }
+    return true
+}
+
+//
+// Tools for checking that a sort function is correct. isSorted only checks
+// that the result is in order, but a sort that returned a slice of zeros
+// would pass that. A correct sort must also:
+//
+// - return a permutation of its input: the same values, the same number of
+//   times each, just rearranged
+// - leave a sorted slice as it is (it's idempotent)
+// - if it's meant to be stable, keep equal values in their original order
 //
-func testSort(sort func([]int)) {
-    data := [][]int{
-        []int{}, []int{1}, []int{1,2}, []int{2,1}, []int{2,2}, 
-        []int{1,2,3}, []int{3,2,1}, []int{2,1,3},
-        []int{8,9,3,1,0,4,-9,3,3,1},
+// Each check returns nil if it passes, or an error describing what's wrong.
+//
+
+//
+// Checks that after has the same values as before, each the same number of
+// times. Counting the values with a map works even if they can't be sorted.
+//
+func checkPermutation[T comparable](before, after []T) error {
+    if len(before) != len(after) {
+        return fmt.Errorf("length changed from %v to %v", len(before), len(after))
+    }
+    counts := map[T]int{}
+    for _, x := range before {
+        counts[x]++
+    }
+    for _, x := range after {
+        counts[x]--
     }
-    for i, lst := range data {
-        fmt.Printf("test %v: sort(%v): ", i+1, lst)
-        sort(lst)
-        fmt.Printf(" %v", lst)
-        if isSorted(lst) {
-            fmt.Println(" passed")
+    for x, n := range counts {
+        if n > 0 {
+            return fmt.Errorf("%v is missing %v time(s)", x, n)
+        } else if n < 0 {
+            return fmt.Errorf("%v was added %v time(s)", x, -n)
+        }
+    }
+    return nil
+}
+
+//
+// Checks that a is sorted according to less.
+//
+func checkOrder[T any](a []T, less func(x, y T) bool) error {
+    for i := 1; i < len(a); i++ {
+        if less(a[i], a[i-1]) {
+            return fmt.Errorf("a[%v] = %v comes after a[%v] = %v", i, a[i], i-1, a[i-1])
+        }
+    }
+    return nil
+}
+
+//
+// Checks that sorting input twice gives the same result as sorting it once.
+// input isn't changed.
+//
+func checkIdempotent[T comparable](sort func([]T), input []T) error {
+    once := slices.Clone(input)
+    sort(once)
+    twice := slices.Clone(once)
+    sort(twice)
+    if !testEq(once, twice) {
+        return fmt.Errorf("sorting %v gave %v, but sorting that gave %v", input, once, twice)
+    }
+    return nil
+}
+
+//
+// A value tagged with its index in the original slice, for checkStable.
+//
+type tagged[T any] struct {
+    value T
+    index int
+}
+
+func (t tagged[T]) String() string {
+    return fmt.Sprintf("%v#%v", t.value, t.index)
+}
+
+//
+// Checks that sort is stable. Each value of input is tagged with its index,
+// and the tagged values are sorted by sort, which must compare just their
+// values (e.g. with less(a.value, b.value)). Then equal values must still
+// be in order of their tags. input isn't changed.
+//
+func checkStable[T any](sort func([]tagged[T]), input []T, less func(x, y T) bool) error {
+    a := make([]tagged[T], len(input))
+    for i, x := range input {
+        a[i] = tagged[T]{x, i}
+    }
+    sort(a)
+    for i := 1; i < len(a); i++ {
+        x, y := a[i-1], a[i]
+        if !less(x.value, y.value) && !less(y.value, x.value) && x.index > y.index {
+            return fmt.Errorf("equal values %v and %v are out of their original order", x, y)
+        }
+    }
+    return nil
+}
+
+//
+// checkStable needs a sort of tagged values, but the sorts in this file
+// sort plain values, comparing all of each one. These adapters turn them
+// into sorts of tagged values that compare just the values.
+//
+// stableOps is for the comparison sorts that take a sortOps (see sortOps
+// below). They're generic, so they can sort the tagged values themselves,
+// with a less that compares just the values. Counting and radix sort don't
+// compare values at all, so they can't be checked this way.
+//
+func stableOps[T any](sort func([]tagged[T], sortOps[tagged[T]]), less func(x, y T) bool) func([]tagged[T]) {
+    ops := lessOps(func(x, y tagged[T]) bool { return less(x.value, y.value) })
+    return func(a []tagged[T]) {
+        sort(a, ops)
+    }
+}
+
+//
+// stableRecorded is for the generic sorts that take a sortRecorder. It sorts
+// just the values, and makes the same swaps and moves on the tags as the
+// sort makes on the values. A recorder for parallelMergeSort is called from
+// several goroutines, but they work on different parts of the slice, so
+// they never touch the same tags.
+//
+func stableRecorded[T cmp.Ordered](sort func([]T, *sortRecorder)) func([]tagged[T]) {
+    return func(a []tagged[T]) {
+        values := make([]T, len(a))
+        tags, saved := make([]int, len(a)), make([]int, len(a))
+        for i, t := range a {
+            values[i], tags[i] = t.value, t.index
+        }
+        sort(values, &sortRecorder{
+            swap: func(i, j int) { tags[i], tags[j] = tags[j], tags[i] },
+            save: func(lo, hi int) { copy(saved[lo:hi], tags[lo:hi]) },
+            move: func(i, from int) { tags[i] = saved[from] },
+        })
+        for i := range a {
+            a[i] = tagged[T]{values[i], tags[i]}
+        }
+    }
+}
+
+//
+// Runs every check except stability on sort, sorting a copy of input.
+//
+func verifySort[T comparable](sort func([]T), input []T, less func(x, y T) bool) error {
+    a := slices.Clone(input)
+    sort(a)
+    if err := checkPermutation(input, a); err != nil {
+        return fmt.Errorf("not a permutation: %v", err)
+    }
+    if err := checkOrder(a, less); err != nil {
+        return fmt.Errorf("not sorted: %v", err)
+    }
+    if err := checkIdempotent(sort, input); err != nil {
+        return fmt.Errorf("not idempotent: %v", err)
+    }
+    return nil
+}
+
+//
+// Binary search: lowerBound(a, x) returns the index of the first value in
+// the sorted slice a that is >= x, and upperBound(a, x) returns the index of
+// the first value that is > x. If there are no such values they return
+// len(a). So the copies of x in a are a[lowerBound(a, x):upperBound(a, x)].
+//
+// Each step halves the part of a that's left to search, so they take
+// O(log n) time.
+//
+func lowerBound[T cmp.Ordered](a []T, x T) int {
+    lo, hi := 0, len(a) // the answer is in the range lo to hi
+    for lo < hi {
+        mid := lo + (hi-lo)/2 // (lo+hi)/2 could overflow
+        if a[mid] < x {
+            lo = mid + 1
         } else {
-            fmt.Println(" FAILED")
+            hi = mid
         }
     }
+    return lo
+}
+
+func upperBound[T cmp.Ordered](a []T, x T) int {
+    lo, hi := 0, len(a)
+    for lo < hi {
+        mid := lo + (hi-lo)/2
+        if a[mid] <= x {
+            lo = mid + 1
+        } else {
+            hi = mid
+        }
+    }
+    return lo
+}
+
+//
+// Returns a new sorted slice with all the values of the sorted slices a and
+// b. When a value is in both, the ones from a come first.
+//
+func mergeSorted[T cmp.Ordered](a, b []T) []T {
+    result := make([]T, 0, len(a)+len(b))
+    i, j := 0, 0
+    for i < len(a) && j < len(b) {
+        if b[j] < a[i] {
+            result = append(result, b[j])
+            j++
+        } else {
+            result = append(result, a[i])
+            i++
+        }
+    }
+    result = append(result, a[i:]...)
+    return append(result, b[j:]...)
+}
+
+//
+// A SortedSlice is a slice that's always kept in ascending sorted order, so
+// values can be found quickly with binary search. Duplicate values are
+// allowed.
+//
+// Insert and Remove take O(n) time, since the values after the insertion
+// point have to be moved, but Contains, Rank and Range take O(log n) time.
+//
+// The zero value is an empty SortedSlice, ready to use.
+//
+type SortedSlice[T cmp.Ordered] struct {
+    items []T
+}
+
+//
+// Returns a SortedSlice holding values. values isn't changed.
+//
+func NewSortedSlice[T cmp.Ordered](values ...T) *SortedSlice[T] {
+    items := append([]T{}, values...)
+    slices.Sort(items)
+    return &SortedSlice[T]{items}
+}
+
+//
+// Inserts x in its sorted position, after any values equal to it.
+//
+func (s *SortedSlice[T]) Insert(x T) {
+    s.items = insert1(x, upperBound(s.items, x), s.items)
+}
+
+//
+// Removes one copy of x, and returns true, or returns false if x isn't
+// there.
+//
+func (s *SortedSlice[T]) Remove(x T) bool {
+    i := lowerBound(s.items, x)
+    if i == len(s.items) || s.items[i] != x {
+        return false
+    }
+    s.items = append(s.items[:i], s.items[i+1:]...)
+    return true
+}
+
+//
+// Returns true if x is in s.
+//
+func (s *SortedSlice[T]) Contains(x T) bool {
+    i := lowerBound(s.items, x)
+    return i < len(s.items) && s.items[i] == x
+}
+
+//
+// Returns the number of values in s that are less than x. If x is in s,
+// that's the index of its first copy.
+//
+func (s *SortedSlice[T]) Rank(x T) int {
+    return lowerBound(s.items, x)
+}
+
+//
+// Returns the values v in s with lo <= v < hi, in sorted order. The result
+// shares memory with s, so copy it before changing s if it needs to be
+// kept.
+//
+func (s *SortedSlice[T]) Range(lo, hi T) []T {
+    i := lowerBound(s.items, lo)
+    j := max(lowerBound(s.items, hi), i)
+    return s.items[i:j]
+}
+
+func (s *SortedSlice[T]) Len() int {
+    return len(s.items)
+}
+
+//
+// Returns the value at index i, i.e. the (i+1)th smallest value.
+//
+func (s *SortedSlice[T]) At(i int) T {
+    return s.items[i]
+}
+
+//
+// Returns the values in s in sorted order. Like Range, the result shares
+// memory with s.
+//
+func (s *SortedSlice[T]) Values() []T {
+    return s.items
+}
+
+//
+// Returns a new SortedSlice with the values of both s and other.
+//
+func (s *SortedSlice[T]) Merge(other *SortedSlice[T]) *SortedSlice[T] {
+    return &SortedSlice[T]{mergeSorted(s.items, other.items)}
+}
+
+func (s *SortedSlice[T]) String() string {
+    return fmt.Sprint(s.items)
+}
+
+//
+// Merge sort that uses goroutines to sort the two halves at the same time.
+//
+// Each call splits its slice in half, sorts the left half in a new
+// goroutine while it sorts the right half itself, and then merges them. A
+// goroutine is cheap, but not free, so slices shorter than parallelCutoff
+// are sorted in the same goroutine; and merge sort is slow on very short
+// slices, so ones shorter than insertionCutoff are insertion sorted.
+//
+// The two halves are separate parts of a (and of temp), so the goroutines
+// never touch the same memory, and need no locks. They only need to wait
+// for each other before merging.
+//
+// Go runs at most GOMAXPROCS goroutines at the same time, so more goroutines
+// than that just take turns. Each level of splitting doubles the number of
+// goroutines, so the splitting stops after depth levels, where 2^depth is
+// at least GOMAXPROCS. With GOMAXPROCS = 1, no goroutines are started at
+// all. GOMAXPROCS is usually the number of CPUs, but it can be set lower,
+// e.g. with go test -cpu.
+//
+const (
+    parallelCutoff  = 1 << 13
+    insertionCutoff = 32
+)
+
+func parallelMergeSort[T cmp.Ordered](a []T, rec ...*sortRecorder) {
+    parallelSortRange(a, make([]T, len(a)), parallelDepth(runtime.GOMAXPROCS(0)), recorderOf(rec))
+}
+
+//
+// Returns how many times to split a slice between goroutines when procs
+// goroutines can run at once: log2(procs), rounded up.
+//
+func parallelDepth(procs int) int {
+    if procs <= 1 {
+        return 0
+    }
+    return bits.Len(uint(procs - 1))
+}
+
+//
+// r is told about each step, with indexes counted from the start of a; the
+// right half's steps are shifted by mid, so they're counted from there too.
+//
+func parallelSortRange[T cmp.Ordered](a, temp []T, depth int, r *sortRecorder) {
+    if depth == 0 || len(a) < parallelCutoff {
+        sequentialSortRange(a, temp, r)
+        return
+    }
+    mid := len(a) / 2
+    done := make(chan bool)
+    go func() {
+        parallelSortRange(a[:mid], temp[:mid], depth-1, r)
+        done <- true
+    }()
+    parallelSortRange(a[mid:], temp[mid:], depth-1, r.shift(mid))
+    <-done // wait for the left half
+    mergeHalves(a, mid, temp, r)
+}
+
+//
+// The same merge sort without goroutines, to compare with.
+//
+func sequentialMergeSort[T cmp.Ordered](a []T, rec ...*sortRecorder) {
+    sequentialSortRange(a, make([]T, len(a)), recorderOf(rec))
+}
+
+func sequentialSortRange[T cmp.Ordered](a, temp []T, r *sortRecorder) {
+    if len(a) <= insertionCutoff {
+        insertionSort(a, r)
+        return
+    }
+    mid := len(a) / 2
+    sequentialSortRange(a[:mid], temp[:mid], r)
+    sequentialSortRange(a[mid:], temp[mid:], r.shift(mid))
+    mergeHalves(a, mid, temp, r)
+}
+
+//
+// Merges the sorted halves a[:mid] and a[mid:], using temp (which is as
+// long as a) as scratch space.
+//
+func mergeHalves[T cmp.Ordered](a []T, mid int, temp []T, r *sortRecorder) {
+    r.compared(mid-1, mid)
+    if a[mid-1] <= a[mid] {
+        return // already in order, which is common for nearly sorted input
+    }
+    copy(temp, a)
+    r.saved(0, len(a))
+    i, j := 0, mid
+    for k := range a {
+        left := j == len(a) // the right half is used up
+        if i < mid && j < len(a) {
+            r.compared(i, j)
+            left = temp[i] <= temp[j]
+        }
+        if left {
+            a[k] = temp[i]
+            r.moved(k, i)
+            i++
+        } else {
+            a[k] = temp[j]
+            r.moved(k, j)
+            j++
+        }
+    }
+}
+
+//
+// Times sequentialMergeSort, parallelMergeSort and sort.Ints on n random
+// ints, and prints the speedup from using goroutines.
+//
+func timeParallelSort(n int) {
+    input := randomInts(n, n, 42)
+    timeIt := func(sort func([]int)) time.Duration {
+        a := slices.Clone(input)
+        start := time.Now()
+        sort(a)
+        elapsed := time.Since(start)
+        if !isSorted(a) {
+            fmt.Println("not sorted!")
+        }
+        return elapsed
+    }
+    sequential := timeIt(func(a []int) { sequentialMergeSort(a) })
+    parallel := timeIt(func(a []int) { parallelMergeSort(a) })
+    library := timeIt(sort.Ints)
+    fmt.Printf("\nsorting %v ints with GOMAXPROCS = %v:\n", n, runtime.GOMAXPROCS(0))
+    fmt.Printf("   sequential merge sort: %v\n", sequential.Round(time.Microsecond))
+    fmt.Printf("     parallel merge sort: %v (%.1fx faster)\n",
+               parallel.Round(time.Microsecond), float64(sequential)/float64(parallel))
+    fmt.Printf("               sort.Ints: %v\n", library.Round(time.Microsecond))
+}
+
+//
+// A library of sorting algorithms. Each one counts the work it does in a
+// sortStats, so they can be compared:
+//
+// - comparisons: how many times two values were compared
+// - swaps: how many times two values were swapped
+// - moves: how many times a value was copied into place some other way,
+//   e.g. by merge sort copying from its temporary slice
+//
+// Counting and radix sort never compare values; they work out where each
+// value goes from the value itself.
+//
+// Every algorithm in sortAlgorithms below takes a *sortStats, which can be
+// nil if the counts aren't needed. If its trace isn't nil, every step is
+// also recorded there (see sortTrace below).
+//
+type sortStats struct {
+    comparisons int
+    swaps       int
+    moves       int
+    trace       *sortTrace
+}
+
+//
+// Returns x < y, and counts a comparison.
+//
+func (st *sortStats) less(x, y int) bool {
+    if st != nil {
+        st.comparisons++
+        if st.trace != nil {
+            st.trace.compare(x, y)
+        }
+    }
+    return x < y
+}
+
+//
+// Swaps a[i] and a[j], and counts a swap.
+//
+func (st *sortStats) swap(a []int, i, j int) {
+    a[i], a[j] = a[j], a[i]
+    if st != nil {
+        st.swaps++
+        if st.trace != nil {
+            st.trace.swap(a, i, j)
+        }
+    }
+}
+
+//
+// Sets a[i] to x, and counts a move.
+//
+func (st *sortStats) move(a []int, i int, x int) {
+    a[i] = x
+    if st != nil {
+        st.moves++
+        if st.trace != nil {
+            st.trace.move(a, i)
+        }
+    }
+}
+
+//
+// Returns a sortRecorder that counts the steps a generic sort takes on a in
+// st, and records them in st.trace if it isn't nil, so the generic sorts can
+// be compared and traced like the algorithms below. If st is nil, it
+// returns nil, which records nothing.
+//
+// The sort has already made each swap or move when it tells the recorder,
+// so a has the new values. A merge's compares are of the values it saved,
+// which might have been overwritten in a since, so the recorder keeps its
+// own copy of them. mergeEnd[k] is the end of the merge using saved[k], or
+// 0 if no merge is.
+//
+// Unlike other recorders, this one isn't safe for several goroutines at
+// once. parallelMergeSort only uses goroutines for slices of at least
+// parallelCutoff values, far more than a trace is for.
+//
+func (st *sortStats) recorder(a []int) *sortRecorder {
+    if st == nil {
+        return nil
+    }
+    saved, mergeEnd := make([]int, len(a)), make([]int, len(a))
+    valueAt := func(k int) int {
+        if mergeEnd[k] != 0 {
+            return saved[k]
+        }
+        return a[k]
+    }
+    return &sortRecorder{
+        compare: func(i, j int) {
+            st.comparisons++
+            if st.trace != nil {
+                st.trace.compare(valueAt(i), valueAt(j))
+            }
+        },
+        swap: func(i, j int) {
+            st.swaps++
+            if st.trace != nil {
+                st.trace.swap(a, i, j)
+            }
+        },
+        save: func(lo, hi int) {
+            copy(saved[lo:hi], a[lo:hi])
+            for k := lo; k < hi; k++ {
+                mergeEnd[k] = hi
+            }
+        },
+        move: func(i, from int) {
+            st.moves++
+            if st.trace != nil {
+                st.trace.move(a, i)
+            }
+            if i+1 == mergeEnd[i] { // the merge's last move
+                for k := i; k >= 0 && mergeEnd[k] == i+1; k-- {
+                    mergeEnd[k] = 0
+                }
+            }
+        },
+    }
+}
+
+//
+// The comparison sorts below take a sortOps, which is how they compare,
+// swap and set values. statsOps makes one that works on a *sortStats, so
+// every step is counted (and traced), and lessOps makes one for a slice of
+// any type, compared with a less function. So the same code that's counted
+// in the comparison table can also sort e.g. the tagged values that
+// checkStable uses.
+//
+type sortOps[T any] struct {
+    less func(x, y T) bool
+    swap func(a []T, i, j int)
+    move func(a []T, i int, x T)
+}
+
+func statsOps(st *sortStats) sortOps[int] {
+    return sortOps[int]{st.less, st.swap, st.move}
+}
+
+func lessOps[T any](less func(x, y T) bool) sortOps[T] {
+    return sortOps[T]{
+        less: less,
+        swap: func(a []T, i, j int) { a[i], a[j] = a[j], a[i] },
+        move: func(a []T, i int, x T) { a[i] = x },
+    }
+}
+
+//
+// Returns sort as a function that takes a *sortStats instead of a sortOps,
+// like the other algorithms in sortAlgorithms.
+//
+func counted(sort func([]int, sortOps[int])) func([]int, *sortStats) {
+    return func(a []int, st *sortStats) {
+        sort(a, statsOps(st))
+    }
+}
+
+//
+// Insertion sort, like insertionSortFunc above, but taking its steps through
+// ops. It's fast for short or nearly sorted slices, so shell sort and
+// timsort use it.
+//
+func insertionSortCounted[T any](a []T, ops sortOps[T]) {
+    for i := 1; i < len(a); i++ {
+        for j := i; j > 0 && ops.less(a[j], a[j-1]); j-- {
+            ops.swap(a, j, j-1)
+        }
+    }
+}
+
+//
+// Merge sort: sort each half, and then merge the two sorted halves. It
+// always does O(n log n) comparisons, and is stable, but needs a temporary
+// slice as big as a.
+//
+func mergeSort[T any](a []T, ops sortOps[T]) {
+    temp := make([]T, len(a))
+    var sortRange func(lo, hi int)
+    sortRange = func(lo, hi int) {
+        if hi-lo < 2 {
+            return
+        }
+        mid := lo + (hi-lo)/2
+        sortRange(lo, mid)
+        sortRange(mid, hi)
+        merge(a, lo, mid, hi, temp, ops)
+    }
+    sortRange(0, len(a))
+}
+
+//
+// Merges the sorted runs a[lo:mid] and a[mid:hi], using temp as scratch
+// space. When values are equal the one from the left run goes first, which
+// makes the merge stable.
+//
+func merge[T any](a []T, lo, mid, hi int, temp []T, ops sortOps[T]) {
+    copy(temp[lo:hi], a[lo:hi])
+    i, j := lo, mid
+    for k := lo; k < hi; k++ {
+        if i < mid && (j >= hi || !ops.less(temp[j], temp[i])) {
+            ops.move(a, k, temp[i])
+            i++
+        } else {
+            ops.move(a, k, temp[j])
+            j++
+        }
+    }
+}
+
+//
+// Quicksort: pick a pivot, partition a into the values less than, equal to,
+// and greater than it, and sort the less and greater parts.
+//
+// The pivot is the median of the first, middle and last values, so sorted
+// and reversed input don't cause the O(n^2) worst case. Partitioning is
+// three-way, so lots of equal values don't either: all the copies of the
+// pivot are put in their final place at once.
+//
+// Recursing on the smaller part and looping on the bigger one keeps the
+// stack depth O(log n).
+//
+func quickSort[T any](a []T, ops sortOps[T]) {
+    lo, hi := 0, len(a)
+    for hi-lo > 1 {
+        pivot := medianOfThree(a, lo, lo+(hi-lo)/2, hi-1, ops)
+        lt, gt := partition3(a, lo, hi, pivot, ops)
+        if lt-lo < hi-gt {
+            quickSort(a[lo:lt], ops)
+            lo = gt
+        } else {
+            quickSort(a[gt:hi], ops)
+            hi = lt
+        }
+    }
+}
+
+//
+// Returns the middle value of a[i], a[j] and a[k].
+//
+func medianOfThree[T any](a []T, i, j, k int, ops sortOps[T]) T {
+    x, y, z := a[i], a[j], a[k]
+    if ops.less(y, x) {
+        x, y = y, x
+    }
+    if ops.less(z, y) {
+        y = z
+        if ops.less(y, x) {
+            y = x
+        }
+    }
+    return y
+}
+
+//
+// Rearranges a[lo:hi] into the values less than pivot in a[lo:lt], the
+// values equal to it in a[lt:gt], and the values greater than it in
+// a[gt:hi].
+//
+// This is Bentley and McIlroy's three-way partition. Like Hoare's partition,
+// i scans right past small values and j scans left past big ones, and when
+// both get stuck a[i] and a[j] are swapped. Values equal to the pivot are
+// swapped out of the way to the two ends, and swapped into the middle at
+// the end:
+//
+//    | == pivot | < pivot |  unknown  | > pivot | == pivot |
+//    lo         p         i           j         q          hi
+//
+// (Dijkstra's simpler "Dutch national flag" partition also works, but it
+// reverses the big values as it goes, which makes sorted input take O(n^2)
+// time even with a median-of-three pivot.)
+//
+func partition3[T any](a []T, lo, hi int, pivot T, ops sortOps[T]) (lt, gt int) {
+    i, j := lo, hi-1
+    p, q := lo, hi-1
+    for {
+        for i <= j && !ops.less(pivot, a[i]) { // a[i] <= pivot
+            if !ops.less(a[i], pivot) {
+                ops.swap(a, p, i)
+                p++
+            }
+            i++
+        }
+        for i <= j && !ops.less(a[j], pivot) { // a[j] >= pivot
+            if !ops.less(pivot, a[j]) {
+                ops.swap(a, j, q)
+                q--
+            }
+            j--
+        }
+        if i > j {
+            break
+        }
+        ops.swap(a, i, j)
+        i++
+        j--
+    }
+
+    //
+    // Now i == j+1. Swap the equal values at the ends into the middle.
+    //
+    for k, m := 0, min(p-lo, i-p); k < m; k++ {
+        ops.swap(a, lo+k, i-1-k)
+    }
+    for k, m := 0, min(hi-1-q, q-j); k < m; k++ {
+        ops.swap(a, i+k, hi-1-k)
+    }
+    return lo + (i - p), hi - (q - j)
+}
+
+//
+// Heapsort: arrange a into a max-heap, where each a[i] is at least as big as
+// its children a[2i+1] and a[2i+2]. Then repeatedly swap the biggest value,
+// a[0], to the end, and fix the heap. It's O(n log n) in the worst case and
+// needs no extra memory, but it isn't stable.
+//
+func heapSort[T any](a []T, ops sortOps[T]) {
+    n := len(a)
+    for i := n/2 - 1; i >= 0; i-- {
+        siftDown(a, i, n, ops)
+    }
+    for end := n - 1; end > 0; end-- {
+        ops.swap(a, 0, end)
+        siftDown(a, 0, end, ops)
+    }
+}
+
+//
+// Moves a[i] down the heap a[:n] until it's no smaller than its children.
+//
+func siftDown[T any](a []T, i, n int, ops sortOps[T]) {
+    for {
+        largest := i
+        left, right := 2*i+1, 2*i+2
+        if left < n && ops.less(a[largest], a[left]) {
+            largest = left
+        }
+        if right < n && ops.less(a[largest], a[right]) {
+            largest = right
+        }
+        if largest == i {
+            return
+        }
+        ops.swap(a, i, largest)
+        i = largest
+    }
+}
+
+//
+// Shell sort: insertion sort on values gap apart, for a shrinking sequence
+// of gaps ending with 1. The big gaps move values a long way quickly, so by
+// the time the gap is 1 the slice is nearly sorted, which insertion sort is
+// fast at.
+//
+// The gaps are Ciura's, which work well in practice, extended by multiplying
+// by 2.25 for big slices.
+//
+func shellSort[T any](a []T, ops sortOps[T]) {
+    gaps := []int{1, 4, 10, 23, 57, 132, 301, 701}
+    for gaps[len(gaps)-1] < len(a)/2 {
+        gaps = append(gaps, gaps[len(gaps)-1]*9/4)
+    }
+    for g := len(gaps) - 1; g >= 0; g-- {
+        gap := gaps[g]
+        for i := gap; i < len(a); i++ {
+            for j := i; j >= gap && ops.less(a[j], a[j-gap]); j -= gap {
+                ops.swap(a, j, j-gap)
+            }
+        }
+    }
+}
+
+//
+// Counting sort: count how many times each value occurs, and then write
+// each value out that many times. It takes O(n + k) time and memory, where
+// k is the difference between the biggest and smallest values, so it's only
+// useful when the values are in a small range.
+//
+// If k is more than about 4n, the counts would use more memory than the
+// values, and for values near math.MinInt and math.MaxInt they wouldn't fit
+// in memory at all. So then it uses radixSort instead, which takes O(n)
+// memory whatever the range. hi-lo can overflow an int, so k is worked out
+// as a uint64, which can hold the difference between any two ints.
+//
+func countingSort(a []int, st *sortStats) {
+    if len(a) < 2 {
+        return
+    }
+    lo, hi := slices.Min(a), slices.Max(a)
+    if uint64(hi)-uint64(lo) >= uint64(4*len(a)+256) {
+        radixSort(a, st)
+        return
+    }
+    counts := make([]int, hi-lo+1)
+    for _, x := range a {
+        counts[x-lo]++
+    }
+    i := 0
+    for v, count := range counts {
+        for ; count > 0; count-- {
+            st.move(a, i, v+lo)
+            i++
+        }
+    }
+}
+
+//
+// Radix sort: counting sort on each byte of the values, starting with the
+// least significant. Each pass is stable, so values with the same high
+// bytes stay in order of their low bytes. It takes O(8n) time for 64-bit
+// ints, whatever their range.
+//
+// Flipping the sign bit makes negative numbers sort before positive ones
+// when the bytes are compared as unsigned numbers.
+//
+func radixSort(a []int, st *sortStats) {
+    key := func(x int, shift uint) int {
+        return int((uint64(x) ^ (1 << 63)) >> shift & 0xff)
+    }
+    if len(a) < 2 {
+        return
+    }
+    result := a
+    temp := make([]int, len(a))
+    for shift := uint(0); shift < 64; shift += 8 {
+        var counts [257]int
+        for _, x := range a {
+            counts[key(x, shift)+1]++
+        }
+        if counts[key(a[0], shift)+1] == len(a) {
+            continue // every value has the same byte here
+        }
+        for b := 1; b < len(counts); b++ {
+            counts[b] += counts[b-1] // counts[b] is where byte b's values start
+        }
+        for _, x := range a {
+            k := key(x, shift)
+            st.move(temp, counts[k], x)
+            counts[k]++
+        }
+        a, temp = temp, a
+    }
+    if &a[0] != &result[0] {
+        // after an odd number of passes the values are in temp
+        for i, x := range a {
+            st.move(result, i, x)
+        }
+    }
+}
+
+//
+// Timsort, the algorithm used by Python and Java: it finds the runs of
+// values that are already in order, and merges them. So it's very fast on
+// data that's partly sorted, which real data often is.
+//
+// Short runs are extended to minRun values with insertion sort, and the
+// runs are kept on a stack whose lengths shrink at least as fast as the
+// Fibonacci numbers, so the merges are balanced. (Real timsort also
+// "gallops" through runs when merging, which this version leaves out.)
+//
+func timSort[T any](a []T, ops sortOps[T]) {
+    n := len(a)
+    minRun := timMinRun(n)
+    temp := make([]T, n)
+
+    type run struct{ start, length int }
+    var stack []run
+
+    mergeAt := func(i int) {
+        x, y := stack[i], stack[i+1]
+        merge(a, x.start, y.start, y.start+y.length, temp, ops)
+        stack[i] = run{x.start, x.length + y.length}
+        stack = append(stack[:i+1], stack[i+2:]...)
+    }
+
+    for lo := 0; lo < n; {
+        //
+        // Find the run starting at lo. A strictly descending run is
+        // reversed; it has to be strictly descending, or reversing it would
+        // change the order of equal values.
+        //
+        hi := lo + 1
+        if hi < n {
+            if ops.less(a[hi], a[lo]) {
+                for hi+1 < n && ops.less(a[hi+1], a[hi]) {
+                    hi++
+                }
+                for i, j := lo, hi; i < j; i, j = i+1, j-1 {
+                    ops.swap(a, i, j)
+                }
+            } else {
+                for hi+1 < n && !ops.less(a[hi+1], a[hi]) {
+                    hi++
+                }
+            }
+            hi++
+        }
+        if hi-lo < minRun {
+            hi = min(lo+minRun, n)
+            insertionSortCounted(a[lo:hi], ops)
+        }
+        stack = append(stack, run{lo, hi - lo})
+        lo = hi
+
+        //
+        // Merge until the lengths of the runs on the stack, from the top
+        // down, satisfy C > B + A and B > A.
+        //
+        for len(stack) > 1 {
+            i := len(stack) - 2
+            if i > 0 && stack[i-1].length <= stack[i].length+stack[i+1].length {
+                if stack[i-1].length < stack[i+1].length {
+                    i--
+                }
+            } else if stack[i].length > stack[i+1].length {
+                break
+            }
+            mergeAt(i)
+        }
+    }
+    for len(stack) > 1 {
+        mergeAt(len(stack) - 2)
+    }
+}
+
+//
+// Returns the minimum run length for timsort: a number from 32 to 64 such
+// that n/minRun is a power of 2, or a little less than one, which makes the
+// final merges balanced.
+//
+func timMinRun(n int) int {
+    r := 0
+    for n >= 64 {
+        r |= n & 1
+        n >>= 1
+    }
+    return n + r
+}
+
+//
+// The sorting algorithms in the library, for testing and comparing.
+//
+var sortAlgorithms = []struct {
+    name string
+    sort func([]int, *sortStats)
+}{
+    {"insertion", counted(insertionSortCounted)},
+    {"merge", counted(mergeSort)},
+    {"quick", counted(quickSort)},
+    {"heap", counted(heapSort)},
+    {"shell", counted(shellSort)},
+    {"counting", countingSort},
+    {"radix", radixSort},
+    {"tim", counted(timSort)},
+}
+
+//
+// The generic sorts, which tell a sortRecorder about their steps instead.
+// sortStats.recorder counts and traces them.
+//
+var recordedSorts = []struct {
+    name string
+    sort func([]int, *sortRecorder)
+}{
+    {"insertionSort", func(a []int, r *sortRecorder) { insertionSort(a, r) }},
+    {"insertionSortFunc", func(a []int, r *sortRecorder) {
+        insertionSortFunc(a, func(x, y int) bool { return x < y }, r)
+    }},
+    {"sequentialMergeSort", func(a []int, r *sortRecorder) { sequentialMergeSort(a, r) }},
+    {"parallelMergeSort", func(a []int, r *sortRecorder) { parallelMergeSort(a, r) }},
+}
+
+//
+// Returns a slice of n "random" ints from 0 to max-1. It uses a simple
+// linear congruential generator with a fixed seed, so every run gets the
+// same numbers, and the comparison table doesn't change from run to run.
+//
+func randomInts(n, max int, seed uint64) []int {
+    a := make([]int, n)
+    for i := range a {
+        seed = seed*6364136223846793005 + 1442695040888963407
+        a[i] = int(seed>>33) % max
+    }
+    return a
+}
+
+//
+// Prints a table of how much work each algorithm does to sort n values,
+// for four kinds of input.
+//
+func compareSorts(n int) {
+    inputs := []struct {
+        name string
+        data []int
+    }{
+        {"random", randomInts(n, n, 1)},
+        {"sorted", make([]int, n)},
+        {"reversed", make([]int, n)},
+        {"many duplicates", randomInts(n, 10, 2)},
+    }
+    for i := 0; i < n; i++ {
+        inputs[1].data[i] = i
+        inputs[2].data[i] = n - i
+    }
+
+    for _, input := range inputs {
+        fmt.Printf("\n%v ints, %v:\n", n, input.name)
+        fmt.Printf("%-10v %12v %12v %12v %12v\n",
+            "algorithm", "comparisons", "swaps", "moves", "time")
+        for _, alg := range sortAlgorithms {
+            a := slices.Clone(input.data)
+            var st sortStats
+            start := time.Now()
+            alg.sort(a, &st)
+            elapsed := time.Since(start)
+            fmt.Printf("%-10v %12v %12v %12v %12v\n",
+                alg.name, st.comparisons, st.swaps, st.moves, elapsed.Round(time.Microsecond))
+        }
+    }
+} // compareSorts
+
+//
+// A trace of the steps a sort takes, for watching how it works. To record
+// one, set the trace field of the sortStats passed to the sort:
+//
+//    a := []int{5, 2, 8, 1}
+//    tr := newSortTrace(a)
+//    quickSort(a, statsOps(&sortStats{trace: tr}))
+//    tr.replay(os.Stdout, 200*time.Millisecond)
+//
+// The generic sorts take a sortRecorder instead, which sortStats.recorder
+// makes:
+//
+//    st := sortStats{trace: newSortTrace(a)}
+//    sequentialMergeSort(a, st.recorder(a))
+//
+// Every comparison, swap and move is recorded, along with a copy of the
+// whole slice after each swap or move, so tracing is only sensible for
+// short slices (a few dozen values).
+//
+type traceStep struct {
+    op    string // "compare", "swap" or "move"
+    i, j  int    // the indexes swapped or moved to, or -1
+    x, y  int    // the values compared or moved
+    state []int  // the slice after the step, or nil if it didn't change
+}
+
+type sortTrace struct {
+    watched []int // the slice being sorted
+    initial []int // a copy of it before sorting
+    steps   []traceStep
+}
+
+func newSortTrace(a []int) *sortTrace {
+    return &sortTrace{watched: a, initial: slices.Clone(a)}
+}
+
+//
+// Returns the index in tr.watched of a[i], or -1 if a[i] isn't part of
+// tr.watched (e.g. it's in merge sort's temporary slice). The algorithms
+// often work on parts of the slice, like a[lo:hi], so i isn't always the
+// same as the index in the whole slice. Comparing addresses finds it.
+//
+func (tr *sortTrace) indexOf(a []int, i int) int {
+    for k := range tr.watched {
+        if &tr.watched[k] == &a[i] {
+            return k
+        }
+    }
+    return -1
+}
+
+func (tr *sortTrace) compare(x, y int) {
+    tr.steps = append(tr.steps, traceStep{op: "compare", i: -1, j: -1, x: x, y: y})
+}
+
+func (tr *sortTrace) swap(a []int, i, j int) {
+    step := traceStep{op: "swap", i: tr.indexOf(a, i), j: tr.indexOf(a, j), x: a[i], y: a[j]}
+    if step.i >= 0 || step.j >= 0 {
+        step.state = slices.Clone(tr.watched)
+    }
+    tr.steps = append(tr.steps, step)
+}
+
+func (tr *sortTrace) move(a []int, i int) {
+    step := traceStep{op: "move", i: tr.indexOf(a, i), j: -1, x: a[i]}
+    if step.i >= 0 {
+        step.state = slices.Clone(tr.watched)
+    }
+    tr.steps = append(tr.steps, step)
+}
+
+//
+// A frame is a picture of the slice: the initial state, and then the state
+// after each step that changed it. highlight is the indexes that just
+// changed, and caption describes the step.
+//
+type traceFrame struct {
+    state     []int
+    highlight []int
+    caption   string
+}
+
+func (tr *sortTrace) frames() []traceFrame {
+    frames := []traceFrame{{tr.initial, nil, "start"}}
+    comparisons := 0
+    for _, step := range tr.steps {
+        switch {
+        case step.op == "compare":
+            comparisons++
+            continue
+        case step.state == nil:
+            continue
+        }
+        var highlight []int
+        for _, k := range []int{step.i, step.j} {
+            if k >= 0 {
+                highlight = append(highlight, k)
+            }
+        }
+        caption := fmt.Sprintf("swap a[%v] and a[%v]", step.i, step.j)
+        if step.op == "move" {
+            caption = fmt.Sprintf("a[%v] = %v", step.i, step.x)
+        }
+        frames = append(frames, traceFrame{step.state, highlight,
+            fmt.Sprintf("%v (%v comparisons so far)", caption, comparisons)})
+    }
+    return frames
+}
+
+//
+// Returns f drawn as an ASCII bar chart, at most height lines tall. Bars
+// that just changed are drawn with @ instead of #. Every bar is at least one
+// line tall, like in writeSVG, so the smallest values (e.g. all of them, if
+// they're all 0) can still be seen.
+//
+func (f traceFrame) render(height int) string {
+    if len(f.state) == 0 {
+        return "\n" + f.caption + "\n" // no bars to draw
+    }
+    lo, hi := slices.Min(f.state), slices.Max(f.state)
+    lo = min(lo, 0)
+    scale := 1.0
+    if hi-lo > height {
+        scale = float64(height) / float64(hi-lo)
+    }
+    barHeight := func(x int) int {
+        return max(int(float64(x-lo)*scale + 0.5), 1)
+    }
+
+    var sb strings.Builder
+    top := barHeight(hi)
+    for row := top; row >= 1; row-- {
+        for k, x := range f.state {
+            switch {
+            case barHeight(x) < row              : sb.WriteString("   ")
+            case slices.Contains(f.highlight, k) : sb.WriteString("  @")
+            default                              : sb.WriteString("  #")
+            }
+        }
+        sb.WriteString("\n")
+    }
+    for _, x := range f.state {
+        fmt.Fprintf(&sb, "%3v", x)
+    }
+    sb.WriteString("\n" + f.caption + "\n")
+    return sb.String()
+}
+
+//
+// Writes every frame of the trace to w as a bar chart. If delay is more
+// than 0, it clears the screen before each frame and waits delay after it,
+// which animates the sort in a terminal.
+//
+func (tr *sortTrace) replay(w io.Writer, delay time.Duration) {
+    frames := tr.frames()
+    for n, f := range frames {
+        if delay > 0 {
+            fmt.Fprint(w, "\033[H\033[2J") // ANSI escape codes to clear the screen
+        }
+        fmt.Fprintf(w, "frame %v/%v\n%v\n", n+1, len(frames), f.render(15))
+        if delay > 0 {
+            time.Sleep(delay)
+        }
+    }
+}
+
+//
+// Writes the trace to w as an animated SVG image, which a web browser can
+// play. Each bar is a rectangle with <animate> elements that step its
+// height and colour through the frames, one frame every frameTime.
+//
+func (tr *sortTrace) writeSVG(w io.Writer, frameTime time.Duration) error {
+    frames := tr.frames()
+    const barWidth, maxHeight, gap = 20, 200, 2
+    lo, hi := 0, 1
+    for _, f := range frames {
+        if len(f.state) > 0 { // slices.Min and slices.Max panic on empty slices
+            lo, hi = min(lo, slices.Min(f.state)), max(hi, slices.Max(f.state))
+        }
+    }
+    height := func(x int) int {
+        return max((x-lo)*maxHeight/(hi-lo), 1)
+    }
+    n := len(tr.initial)
+    duration := fmt.Sprintf("%.3fs", (frameTime * time.Duration(len(frames))).Seconds())
+
+    var sb strings.Builder
+    fmt.Fprintf(&sb, "<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%v\" height=\"%v\">\n",
+                n*(barWidth+gap)+gap, maxHeight+gap)
+    for k := 0; k < n; k++ {
+        var heights, ys, colours []string
+        for _, f := range frames {
+            h := height(f.state[k])
+            heights = append(heights, fmt.Sprint(h))
+            ys = append(ys, fmt.Sprint(maxHeight+gap-h))
+            if slices.Contains(f.highlight, k) {
+                colours = append(colours, "crimson")
+            } else {
+                colours = append(colours, "steelblue")
+            }
+        }
+        fmt.Fprintf(&sb, "  <rect x=\"%v\" y=\"%v\" width=\"%v\" height=\"%v\" fill=\"%v\">\n",
+                    gap+k*(barWidth+gap), ys[0], barWidth, heights[0], colours[0])
+        for _, anim := range []struct {
+            attr   string
+            values []string
+        }{{"height", heights}, {"y", ys}, {"fill", colours}} {
+            fmt.Fprintf(&sb, "    <animate attributeName=\"%v\" values=\"%v\" dur=\"%v\" "+
+                        "calcMode=\"discrete\" repeatCount=\"indefinite\"/>\n",
+                        anim.attr, strings.Join(anim.values, ";"), duration)
+        }
+        sb.WriteString("  </rect>\n")
+    }
+    sb.WriteString("</svg>\n")
+    _, err := io.WriteString(w, sb.String())
+    return err
+}
+
+//
+// Sorts a copy of a with the algorithm called name, which is one of
+// sortAlgorithms or recordedSorts, and returns its trace, or nil if there's
+// no such algorithm.
+//
+func traceAlgorithm(name string, a []int) (*sortTrace, sortStats) {
+    a = slices.Clone(a)
+    st := sortStats{trace: newSortTrace(a)}
+    for _, alg := range sortAlgorithms {
+        if alg.name == name {
+            alg.sort(a, &st)
+            return st.trace, st
+        }
+    }
+    for _, alg := range recordedSorts {
+        if alg.name == name {
+            alg.sort(a, st.recorder(a))
+            return st.trace, st
+        }
+    }
+    return nil, sortStats{}
 }
 
 func main() {
-    testInsert(insert2)
-    testSort(insertionSort)
+    //
+    // "go run sort.go trace quick" shows quicksort sorting 20 random numbers,
+    // and "go run sort.go svg quick >quick.svg" saves it as an animation. Any
+    // name in sortAlgorithms or recordedSorts works, e.g. insertionSort.
+    //
+    if len(os.Args) == 3 && (os.Args[1] == "trace" || os.Args[1] == "svg") {
+        tr, _ := traceAlgorithm(os.Args[2], randomInts(20, 20, uint64(time.Now().UnixNano())))
+        if tr == nil {
+            fmt.Println("unknown algorithm:", os.Args[2])
+        } else if os.Args[1] == "trace" {
+            tr.replay(os.Stdout, 150*time.Millisecond)
+        } else if err := tr.writeSVG(os.Stdout, 150*time.Millisecond); err != nil {
+            fmt.Println(err)
+        }
+        return
+    }
+
+    fmt.Println(insert1(9, 2, []int{3, 2, 1, 4}))
+    fmt.Println(insert2("b", 1, []string{"a", "c"}))
+
+    a := []int{8, 9, 3, 1, 0, 4, -9, 3, 3, 1}
+    insertionSort(a)
+    fmt.Println(a, isSorted(a))
+
+    s := NewSortedSlice(5, 1, 4)
+    s.Insert(3)
+    s.Insert(4)
+    fmt.Println(s, s.Contains(4), s.Rank(4), s.Range(2, 5))
+
+    compareSorts(2000)
+    timeParallelSort(2000000)
 }
@@ -21,13 +21,36 @@ interface:
 The sort package also has a number of handy type-specific functions for
 sorting slices of of built-in types.
 
+Writing a new sort.Interface type for every way of sorting, like ByName and
+ByAge, gets repetitive: only Less is different each time. So this file uses
+a Comparator instead, which is a function that compares two values:
+
+    byName := By(func(p Person) string { return p.name })
+    byAge := By(func(p Person) int { return p.age })
+
+    // sort by name, and people with the same name by age, oldest first
+    slices.SortFunc(people, byName.Then(byAge.Reverse()))
+
+    // the same thing using sort.Sort
+    sort.Sort(byName.Then(byAge.Reverse()).Sorter(people))
+
+The accent and case tables are in accents.go, which wordcount.go uses too,
+so run this file along with it. The tests are in sortInterface_test.go:
+
+    $ go run sortInterface.go accents.go
+    $ go test sortInterface.go accents.go sortInterface_test.go
+
 */
 
 package main
 
 import (
+    "cmp"
     "fmt"
+    "slices"
     "sort"
+    "strings"
+    "unicode/utf8"
 )
 
 type Person struct {
@@ -40,39 +63,262 @@ func (p Person) String() string {
 }
 
 //
-// sorting by name
+// A Comparator returns a negative number if a comes before b, a positive
+// number if a comes after b, and 0 if they're equal (i.e. their order
+// doesn't matter). This is the same kind of function that cmp.Compare is,
+// and that slices.SortFunc takes.
 //
-type ByName []Person
+// The methods below treat a nil Comparator as saying all values are equal,
+// so it's safe to chain one with Then.
+//
+type Comparator[T any] func(a, b T) int
 
-func (p ByName) Len() int {
-    return len(p)
+//
+// Returns a Comparator that compares values by the key that key extracts
+// from them, in ascending order, e.g. By(func(p Person) int { return p.age })
+// compares people by their age.
+//
+func By[T any, K cmp.Ordered](key func(T) K) Comparator[T] {
+    return func(a, b T) int {
+        return cmp.Compare(key(a), key(b))
+    }
 }
 
-func (p ByName) Less(i, j int) bool {
-    return p[i].name < p[j].name
+//
+// Compares a and b with c. A nil c says they are equal.
+//
+func (c Comparator[T]) Compare(a, b T) int {
+    if c == nil {
+        return 0
+    }
+    return c(a, b)
 }
 
-func (p ByName) Swap(i, j int) {
-    p[i], p[j] = p[j], p[i]
+//
+// Returns true if a comes before b. This is the kind of function
+// sort.Slice and sort.Interface's Less need.
+//
+func (c Comparator[T]) Less(a, b T) bool {
+    return c.Compare(a, b) < 0
 }
 
 //
-// sorting by age
+// Returns a Comparator that sorts in the opposite order to c, i.e.
+// descending instead of ascending.
 //
-type ByAge []Person
+func (c Comparator[T]) Reverse() Comparator[T] {
+    return func(a, b T) int {
+        return c.Compare(b, a)
+    }
+}
 
-func (p ByAge) Len() int {
-    return len(p)
+//
+// Returns a Comparator that compares with c first, and if c says two values
+// are equal, compares them with next. Then can be chained to sort by as many
+// keys as needed, e.g. byName.Then(byAge).Then(byCity).
+//
+func (c Comparator[T]) Then(next Comparator[T]) Comparator[T] {
+    return func(a, b T) int {
+        if result := c.Compare(a, b); result != 0 {
+            return result
+        }
+        return next.Compare(a, b)
+    }
 }
 
-func (p ByAge) Less(i, j int) bool {
-    return p[i].age < p[j].age
+//
+// Returns a Comparator for pointers that puts nil pointers before all the
+// others, and compares non-nil pointers by what they point to using c.
+//
+func NilsFirst[T any](c Comparator[T]) Comparator[*T] {
+    return func(a, b *T) int {
+        switch {
+        case a == nil && b == nil : return 0
+        case a == nil             : return -1
+        case b == nil             : return 1
+        default                   : return c.Compare(*a, *b)
+        }
+    }
 }
 
-func (p ByAge) Swap(i, j int) {
-    p[i], p[j] = p[j], p[i]
+//
+// Like NilsFirst, but puts the nil pointers after all the others.
+//
+func NilsLast[T any](c Comparator[T]) Comparator[*T] {
+    first := NilsFirst(c)
+    return func(a, b *T) int {
+        if (a == nil) != (b == nil) {
+            return -first(a, b)
+        }
+        return first(a, b)
+    }
+}
+
+//
+// sortable is a sort.Interface for any slice, so a new type like ByName
+// isn't needed for each way of sorting.
+//
+type sortable[T any] struct {
+    items []T
+    cmp   Comparator[T]
+}
+
+func (s sortable[T]) Len() int {
+    return len(s.items)
+}
+
+func (s sortable[T]) Less(i, j int) bool {
+    return s.cmp.Less(s.items[i], s.items[j])
 }
 
+func (s sortable[T]) Swap(i, j int) {
+    s.items[i], s.items[j] = s.items[j], s.items[i]
+}
+
+//
+// Returns a sort.Interface that sorts items using c, e.g.
+// sort.Sort(byAge.Sorter(people)).
+//
+func (c Comparator[T]) Sorter(items []T) sort.Interface {
+    return sortable[T]{items, c}
+}
+
+//
+// Collation options for comparing strings. Comparing strings with < compares
+// their bytes, so "Zia" < "bob" (upper case letters come before lower case
+// ones), "Zoë" > "Zoey" ('ë' comes after every ASCII letter), and "item10" <
+// "item2". Combine these flags with | to fix that, e.g.
+//
+//    ByString(func(p Person) string { return p.name }, IgnoreCase|Natural)
+//
+// This isn't full Unicode collation, which needs the golang.org/x/text/collate
+// package. IgnoreCase uses simple case folding (see foldRune in accents.go),
+// so "ß" doesn't equal "SS", and IgnoreAccents only knows the accented
+// letters in the compositions table in accents.go.
+//
+type Collation int
+
+const (
+    IgnoreCase    Collation = 1 << iota // "bob" == "Bob"
+    IgnoreAccents                       // "Zoë" == "Zoe"
+    Natural                             // "item2" < "item10"
+)
+
+//
+// Returns a Comparator for strings using the collation options in opts.
+//
+// Strings that are equal apart from the options (e.g. "bob" and "Bob" with
+// IgnoreCase) compare equal, so a chained comparator gets to decide their
+// order. To always put them in the same order, whatever order they started
+// in, chain a byte-wise comparison at the end:
+//
+//    Collate(IgnoreCase).Then(Collate(0))
+//
+func Collate(opts Collation) Comparator[string] {
+    return func(a, b string) int {
+        fa, fb := foldString(a, opts), foldString(b, opts)
+        if opts&Natural != 0 {
+            return naturalCompare(fa, fb)
+        }
+        return cmp.Compare(fa, fb)
+    }
+}
+
+//
+// Like By, but for string keys, compared using the collation options in
+// opts.
+//
+func ByString[T any](key func(T) string, opts Collation) Comparator[T] {
+    collate := Collate(opts)
+    return func(a, b T) int {
+        return collate(key(a), key(b))
+    }
+}
+
+//
+// Returns s with its case and/or accents removed, as opts says. Either one
+// also normalizes s, so a precomposed letter and the same letter written
+// with a combining accent are equal.
+//
+func foldString(s string, opts Collation) string {
+    if opts&IgnoreAccents != 0 {
+        s = removeAccents(s)
+    } else if opts&IgnoreCase != 0 {
+        s = nfc(s)
+    }
+    if opts&IgnoreCase != 0 {
+        s = strings.Map(foldRune, s)
+    }
+    return s
+}
+
+//
+// Compares a and b in "natural" order: runs of digits are compared by their
+// numeric value, so "item2" < "item10". Everything else is compared a
+// character at a time.
+//
+func naturalCompare(a, b string) int {
+    for a != "" && b != "" {
+        if isDigit(a[0]) && isDigit(b[0]) {
+            da, db := digitPrefix(a), digitPrefix(b)
+            if result := compareDigits(da, db); result != 0 {
+                return result
+            }
+            a, b = a[len(da):], b[len(db):]
+            continue
+        }
+        ra, sizeA := utf8.DecodeRuneInString(a)
+        rb, sizeB := utf8.DecodeRuneInString(b)
+        if ra != rb {
+            return cmp.Compare(ra, rb)
+        }
+        a, b = a[sizeA:], b[sizeB:]
+    }
+    return cmp.Compare(len(a), len(b)) // the one that ran out first is less
+}
+
+func isDigit(c byte) bool {
+    return '0' <= c && c <= '9'
+}
+
+//
+// Returns the digits at the start of s.
+//
+func digitPrefix(s string) string {
+    i := 0
+    for i < len(s) && isDigit(s[i]) {
+        i++
+    }
+    return s[:i]
+}
+
+//
+// Compares two strings of digits by their numeric value. The digits aren't
+// converted to ints, so any number of digits works without overflowing:
+// after removing leading zeros, the longer number is bigger, and numbers of
+// the same length compare the same way as strings.
+//
+func compareDigits(a, b string) int {
+    a, b = strings.TrimLeft(a, "0"), strings.TrimLeft(b, "0")
+    if len(a) != len(b) {
+        return cmp.Compare(len(a), len(b))
+    }
+    return cmp.Compare(a, b)
+}
+
+//
+// Comparators for sorting people.
+//
+var (
+    byName = By(func(p Person) string { return p.name })
+    byAge  = By(func(p Person) int { return p.age })
+
+    // ignores case and accents, and puts "Bob 2" before "Bob 10"; names
+    // that are still equal, like "Bob" and "bob", are compared byte-wise
+    byNameCollated = ByString(func(p Person) string { return p.name },
+                              IgnoreCase|IgnoreAccents|Natural).Then(byName)
+)
+
 func main() {
     people := []Person{
         Person{"Bob", 20}, Person{"Barb", 30}, Person{"Zia", 40},
@@ -80,9 +326,19 @@ func main() {
     }
     fmt.Println("unsorted:", people)
     
-    sort.Sort(ByName(people))
+    sort.Sort(byName.Sorter(people))
     fmt.Println(" by name:", people)
 
-    sort.Sort(ByAge(people))
+    sort.Sort(byAge.Sorter(people))
     fmt.Println("  by age:", people)
+
+    slices.SortFunc(people, byName.Then(byAge.Reverse()))
+    fmt.Println(" by name, then age descending:", people)
+
+    people = append(people, Person{"bob", 18}, Person{"Émile", 22},
+                            Person{"Bob 10", 70}, Person{"Bob 2", 45})
+    slices.SortFunc(people, byName)
+    fmt.Println(" by name, byte-wise:", people)
+    slices.SortFunc(people, byNameCollated)
+    fmt.Println(" by name, collated:", people)
 }
@@ -0,0 +1,237 @@
+// sortInterface_test.go
+
+//
+// Tests for the comparators and collation options in sortInterface.go. Run
+// them like this:
+//
+//    $ go test sortInterface.go accents.go sortInterface_test.go
+//
+
+package main
+
+import (
+    "fmt"
+    "slices"
+    "sort"
+    "testing"
+)
+
+func testPeople() []Person {
+    return []Person{
+        {"Bob", 20}, {"Barb", 30}, {"Zia", 40},
+        {"Bob", 32}, {"Warren", 65}, {"Asa", 50},
+    }
+}
+
+func TestComparator(t *testing.T) {
+    tests := []struct {
+        name     string
+        sort     func(p []Person)
+        expected string
+    }{
+        {"name, then age descending",
+         func(p []Person) { slices.SortFunc(p, byName.Then(byAge.Reverse())) },
+         "[{Asa, 50} {Barb, 30} {Bob, 32} {Bob, 20} {Warren, 65} {Zia, 40}]"},
+        {"sort.Sort",
+         func(p []Person) { sort.Sort(byName.Then(byAge.Reverse()).Sorter(p)) },
+         "[{Asa, 50} {Barb, 30} {Bob, 32} {Bob, 20} {Warren, 65} {Zia, 40}]"},
+        {"age descending",
+         func(p []Person) { sort.Sort(byAge.Reverse().Sorter(p)) },
+         "[{Warren, 65} {Asa, 50} {Zia, 40} {Bob, 32} {Barb, 30} {Bob, 20}]"},
+
+        // sort.Slice isn't stable, so only the people without a tie have a
+        // known place.
+        {"sort.Slice name descending",
+         func(p []Person) {
+             sort.Slice(p, func(i, j int) bool { return byName.Reverse().Less(p[i], p[j]) })
+             p[2], p[3] = Person{"Bob", 0}, Person{"Bob", 0}
+         },
+         "[{Zia, 40} {Warren, 65} {Bob, 0} {Bob, 0} {Barb, 30} {Asa, 50}]"},
+
+        // Reversing a chain reverses every key in it.
+        {"reversed chain",
+         func(p []Person) { slices.SortFunc(p, byName.Then(byAge).Reverse()) },
+         "[{Zia, 40} {Warren, 65} {Bob, 32} {Bob, 20} {Barb, 30} {Asa, 50}]"},
+
+        // A nil Comparator says everything is equal, so a stable sort leaves
+        // the order alone. slices.SortStableFunc would call a nil function,
+        // so pass it the Compare method instead.
+        {"nil comparator",
+         func(p []Person) {
+             var none Comparator[Person]
+             slices.SortStableFunc(p, none.Compare)
+         },
+         fmt.Sprint(testPeople())},
+        {"nil then age",
+         func(p []Person) {
+             var none Comparator[Person]
+             slices.SortStableFunc(p, none.Then(byAge))
+         },
+         "[{Bob, 20} {Barb, 30} {Bob, 32} {Zia, 40} {Asa, 50} {Warren, 65}]"},
+    }
+    for _, test := range tests {
+        p := testPeople()
+        test.sort(p)
+        if got := fmt.Sprint(p); got != test.expected {
+            t.Errorf("%v: got %v, expected %v", test.name, got, test.expected)
+        }
+    }
+}
+
+//
+// Slices of pointers can contain nil.
+//
+func TestNils(t *testing.T) {
+    names := func(ps []*Person) string {
+        s := ""
+        for _, p := range ps {
+            if p == nil {
+                s += "nil "
+            } else {
+                s += p.name + " "
+            }
+        }
+        return s
+    }
+    tests := []struct {
+        name     string
+        cmp      Comparator[*Person]
+        expected string
+    }{
+        {"nils first", NilsFirst(byName), "nil nil Asa Barb Bob Zia "},
+        {"nils last", NilsLast(byName), "Asa Barb Bob Zia nil nil "},
+        {"nils last, descending", NilsLast(byName.Reverse()), "Zia Bob Barb Asa nil nil "},
+    }
+    for _, test := range tests {
+        p := testPeople()
+        ps := []*Person{&p[0], nil, &p[1], &p[2], nil, &p[5]}
+        slices.SortFunc(ps, test.cmp)
+        if got := names(ps); got != test.expected {
+            t.Errorf("%v: got %v, expected %v", test.name, got, test.expected)
+        }
+    }
+}
+
+func TestCollate(t *testing.T) {
+    tests := []struct {
+        name     string
+        opts     Collation
+        names    []string
+        expected string
+    }{
+        {"byte-wise", 0, []string{"bob", "Zia", "Asa"}, "[Asa Zia bob]"},
+        {"IgnoreCase", IgnoreCase, []string{"bob", "Zia", "Asa"}, "[Asa bob Zia]"},
+
+        {"accents byte-wise", 0, []string{"Zoë", "Zoey", "Zoe"}, "[Zoe Zoey Zoë]"},
+        {"IgnoreAccents", IgnoreAccents, []string{"Zoë", "Zoey", "Zoe"}, "[Zoe Zoë Zoey]"},
+        {"accents and case", IgnoreCase|IgnoreAccents, []string{"émile", "Eve", "Edith"},
+         "[Edith émile Eve]"},
+
+        {"natural", Natural, []string{"item10", "item2", "item1", "item20"},
+         "[item1 item2 item10 item20]"},
+        {"natural byte-wise", 0, []string{"item10", "item2", "item1", "item20"},
+         "[item1 item10 item2 item20]"},
+        {"natural leading zeros", Natural, []string{"a010", "a9", "a10", "a0010"},
+         "[a9 a0010 a010 a10]"},
+        {"natural mixed", Natural, []string{"x2y10", "x2y9", "x10y1", "x2"},
+         "[x2 x2y9 x2y10 x10y1]"},
+        {"natural huge numbers", Natural,
+         []string{"n123456789012345678901234567890", "n99999999999999999999"},
+         "[n99999999999999999999 n123456789012345678901234567890]"},
+        {"natural ignore case", Natural|IgnoreCase, []string{"File10", "file9", "FILE1"},
+         "[FILE1 file9 File10]"},
+    }
+    // Strings that are equal apart from the options, like "Zoe" and "Zoë"
+    // with IgnoreAccents, are put in byte-wise order.
+    for _, test := range tests {
+        names := slices.Clone(test.names)
+        slices.SortFunc(names, Collate(test.opts).Then(Collate(0)))
+        if got := fmt.Sprint(names); got != test.expected {
+            t.Errorf("%v: got %v, expected %v", test.name, got, test.expected)
+        }
+    }
+
+    //
+    // Strings that are equal apart from the options compare equal, so a
+    // stable sort keeps them in order, and a chained comparator decides.
+    //
+    names := []string{"bob", "Bob", "BOB"}
+    slices.SortStableFunc(names, Collate(IgnoreCase))
+    if got := fmt.Sprint(names); got != "[bob Bob BOB]" {
+        t.Errorf("IgnoreCase ties, stable: got %v, expected [bob Bob BOB]", got)
+    }
+    slices.SortFunc(names, Collate(IgnoreCase).Then(Collate(0)))
+    if got := fmt.Sprint(names); got != "[BOB Bob bob]" {
+        t.Errorf("IgnoreCase ties, then byte-wise: got %v, expected [BOB Bob bob]", got)
+    }
+}
+
+func TestCollateEqual(t *testing.T) {
+    tests := []struct {
+        opts Collation
+        a, b string
+    }{
+        {IgnoreCase, "Émile", "éMILE"},
+        {IgnoreCase, "ΣΟΦΟΣ", "σοφος"}, // final sigma
+        {IgnoreCase, "ΣΟΦΟΣ", "σοφοσ"},
+        {IgnoreCase, "Zo\u00eb", "ZOE\u0308"}, // precomposed and combining
+        {IgnoreAccents, "Nguyễn", "Nguyen"},
+        {IgnoreAccents, "Ελλάδα", "Ελλαδα"},
+        {IgnoreCase|IgnoreAccents, "ЁЛКА", "елка"},
+    }
+    for _, test := range tests {
+        if got := Collate(test.opts)(test.a, test.b); got != 0 {
+            t.Errorf("Collate(%v)(%q, %q) = %v, expected 0", test.opts, test.a, test.b, got)
+        }
+    }
+
+    // Simple case folding can't turn one letter into two.
+    if Collate(IgnoreCase)("straße", "STRASSE") == 0 {
+        t.Errorf("Collate(IgnoreCase) folded ß to ss")
+    }
+}
+
+func TestRemoveAccents(t *testing.T) {
+    tests := []struct {
+        s, expected string
+    }{
+        {"Émile Zoë Ångström Dvořák", "Emile Zoe Angstrom Dvorak"},
+        {"Zoe\u0308", "Zoe"}, // a combining accent
+        {"Nguyễn Thị Minh Khai", "Nguyen Thi Minh Khai"},
+        {"ǖ ệ", "u e"}, // two accents
+        {"Ελλάδα Ρώμη", "Ελλαδα Ρωμη"},
+        {"Ёлка", "Елка"},
+        {"Øresund Łódź", "Øresund Łodz"}, // ø and ł aren't accented letters
+    }
+    for _, test := range tests {
+        if got := removeAccents(test.s); got != test.expected {
+            t.Errorf("removeAccents(%q) = %q, expected %q", test.s, got, test.expected)
+        }
+    }
+
+    // A precomposed letter and one with a combining accent are the same
+    // letter.
+    if a, b := foldString("Zo\u00eb", IgnoreAccents), foldString("Zoe\u0308", IgnoreAccents); a != b {
+        t.Errorf("precomposed %q != combining %q", a, b)
+    }
+    if got := Collate(IgnoreAccents)("Zoe\u0308x", "Zo\u00eby"); got >= 0 {
+        t.Errorf("Collate(IgnoreAccents)(\"Zoe\\u0308x\", \"Zo\\u00eby\") = %v, expected < 0", got)
+    }
+}
+
+//
+// ByString works with any key, and chains like any other Comparator. "Bob"
+// and "bob" are equal, so byAge decides their order.
+//
+func TestByString(t *testing.T) {
+    people := []Person{
+        {"bob", 20}, {"Émile", 30}, {"Zia", 40}, {"Bob", 32}, {"emma", 25},
+    }
+    slices.SortFunc(people,
+        ByString(func(p Person) string { return p.name }, IgnoreCase|IgnoreAccents).
+            Then(byAge))
+    expected := "[{bob, 20} {Bob, 32} {Émile, 30} {emma, 25} {Zia, 40}]"
+    if got := fmt.Sprint(people); got != expected {
+        t.Errorf("got %v, expected %v", got, expected)
+    }
+}
@@ -0,0 +1,883 @@
+// sort_test.go
+
+//
+// Tests for sort.go. Run them like this:
+//
+//    $ go test sort.go sort_test.go
+//
+// The Fuzz functions are fuzz tests: go test -fuzz=FuzzSort$ (or FuzzInsert)
+// calls them with lots of randomly changed inputs, starting from the
+// examples given to f.Add, and reports any input that makes them fail.
+// Without -fuzz, they're run on just the f.Add examples.
+//
+
+package main
+
+import (
+    "encoding/xml"
+    "fmt"
+    "io"
+    "math"
+    "slices"
+    "sort"
+    "strings"
+    "sync"
+    "testing"
+    "time"
+)
+
+//
+// The insert cases from the original testInsert, plus out of range indexes.
+//
+var insertTests = []struct {
+    x        int
+    i        int
+    lst      []int
+    expected []int
+}{
+    {9, 0, []int{},          []int{9}},
+    {9, 0, []int{0},         []int{9,0}},
+    {9, 1, []int{0},         []int{0,9}},
+    {9, 0, []int{0,1,2,3,4}, []int{9,0,1,2,3,4}},
+    {9, 1, []int{0,1,2,3,4}, []int{0,9,1,2,3,4}},
+    {9, 2, []int{0,1,2,3,4}, []int{0,1,9,2,3,4}},
+    {9, 3, []int{0,1,2,3,4}, []int{0,1,2,9,3,4}},
+    {9, 4, []int{0,1,2,3,4}, []int{0,1,2,3,9,4}},
+    {9, 5, []int{0,1,2,3,4}, []int{0,1,2,3,4,9}},
+    {9, -1, []int{0,1},      []int{9,0,1}},
+    {9, 3, []int{0,1},       []int{0,1,9}},
+    {9, math.MinInt, nil,    []int{9}},
+    {9, math.MaxInt, []int{0}, []int{0,9}},
+}
+
+//
+// The insert functions being tested.
+//
+var inserts = []struct {
+    name   string
+    insert func(int, int, []int) []int
+}{
+    {"insert1", insert1[int]},
+    {"insert2", insert2[int]},
+}
+
+func TestInsert(t *testing.T) {
+    for _, ins := range inserts {
+        for _, test := range insertTests {
+            lst := slices.Clone(test.lst)
+            result := ins.insert(test.x, test.i, lst)
+            if !testEq(result, test.expected) {
+                t.Errorf("%v(%v, %v, %v) = %v, expected %v",
+                         ins.name, test.x, test.i, test.lst, result, test.expected)
+            }
+            if !testEq(lst, test.lst) {
+                t.Errorf("%v(%v, %v, %v) changed its input to %v",
+                         ins.name, test.x, test.i, test.lst, lst)
+            }
+        }
+    }
+}
+
+func TestInsertGeneric(t *testing.T) {
+    for _, insert := range []func(string, int, []string) []string{insert1, insert2} {
+        lst := []string{"a", "b"}
+        tests := []struct {
+            i        int
+            expected []string
+        }{
+            {1, []string{"a", "x", "b"}},
+            {-5, []string{"x", "a", "b"}},
+            {99, []string{"a", "b", "x"}},
+        }
+        for _, test := range tests {
+            if result := insert("x", test.i, lst); !testEq(result, test.expected) {
+                t.Errorf("insert(\"x\", %v, %q) = %q, expected %q", test.i, lst, result, test.expected)
+            }
+        }
+    }
+    if result := insert2(2.5, 2, []float64{1, 2, 3}); !testEq(result, []float64{1, 2, 2.5, 3}) {
+        t.Errorf("insert2(2.5, 2, [1 2 3]) = %v", result)
+    }
+}
+
+//
+// Every sorting function in sort.go, as a func([]int).
+//
+func allSorts() map[string]func([]int) {
+    sorts := map[string]func([]int){
+        "insertionSort": func(a []int) { insertionSort(a) },
+        "insertionSortFunc": func(a []int) {
+            insertionSortFunc(a, func(x, y int) bool { return x < y })
+        },
+        "parallelMergeSort": func(a []int) { parallelMergeSort(a) },
+        "sequentialMergeSort": func(a []int) { sequentialMergeSort(a) },
+    }
+    for _, alg := range sortAlgorithms {
+        sorts[alg.name] = func(a []int) { alg.sort(a, nil) }
+    }
+    return sorts
+}
+
+//
+// Checks that sorted is a sorted permutation of input.
+//
+func checkSorted(t *testing.T, name string, input, sorted []int) {
+    t.Helper()
+    if !isSorted(sorted) || checkPermutation(input, sorted) != nil {
+        if len(input) <= 20 {
+            t.Errorf("%v(%v) = %v", name, input, sorted)
+        } else {
+            t.Errorf("%v on %v ints isn't a sorted permutation", name, len(input))
+        }
+    }
+}
+
+//
+// The inputs from the original testSort.
+//
+var sortTests = [][]int{
+    []int{}, []int{1}, []int{1,2}, []int{2,1}, []int{2,2},
+    []int{1,2,3}, []int{3,2,1}, []int{2,1,3},
+    []int{8,9,3,1,0,4,-9,3,3,1},
+}
+
+func TestSort(t *testing.T) {
+    tests := slices.Clone(sortTests)
+    for n := 10; n <= 10000; n *= 10 {
+        tests = append(tests, randomInts(n, n, uint64(n)), randomInts(n, 5, uint64(n)))
+    }
+    for name, sort := range allSorts() {
+        t.Run(name, func(t *testing.T) {
+            for _, input := range tests {
+                a := slices.Clone(input)
+                sort(a)
+                checkSorted(t, name, input, a)
+            }
+        })
+    }
+}
+
+//
+// Compares parallelMergeSort with sort.Ints on random inputs, including
+// sizes around the cutoffs where it switches between goroutines, merge
+// sort and insertion sort.
+//
+func TestParallelMergeSort(t *testing.T) {
+    sizes := []int{0, 1, 2, insertionCutoff, insertionCutoff + 1,
+                   parallelCutoff - 1, parallelCutoff, parallelCutoff + 1,
+                   5*parallelCutoff + 3, 1000000}
+    for _, n := range sizes {
+        for _, max := range []int{10, n + 1} {
+            input := randomInts(n, max, uint64(n+max))
+            expected := slices.Clone(input)
+            sort.Ints(expected)
+            a := slices.Clone(input)
+            parallelMergeSort(a)
+            if !testEq(a, expected) {
+                t.Errorf("parallelMergeSort of %v ints from 0 to %v is wrong", n, max-1)
+            }
+
+            // parallelMergeSort starts no goroutines if GOMAXPROCS is 1,
+            // so make sure they're tested too.
+            a = slices.Clone(input)
+            parallelSortRange(a, make([]int, n), 3, nil)
+            if !testEq(a, expected) {
+                t.Errorf("parallelSortRange of %v ints from 0 to %v is wrong", n, max-1)
+            }
+            a = slices.Clone(input)
+            sequentialMergeSort(a)
+            if !testEq(a, expected) {
+                t.Errorf("sequentialMergeSort of %v ints from 0 to %v is wrong", n, max-1)
+            }
+        }
+    }
+
+    words := []string{"pear", "apple", "fig", "banana"}
+    parallelMergeSort(words)
+    if !testEq(words, []string{"apple", "banana", "fig", "pear"}) {
+        t.Errorf("parallelMergeSort(strings) = %q", words)
+    }
+}
+
+func TestParallelDepth(t *testing.T) {
+    tests := []struct {
+        procs, depth int
+    }{
+        {0, 0}, {1, 0}, {2, 1}, {3, 2}, {4, 2}, {5, 3}, {8, 3}, {9, 4}, {64, 6},
+    }
+    for _, test := range tests {
+        if got := parallelDepth(test.procs); got != test.depth {
+            t.Errorf("parallelDepth(%v) = %v, expected %v", test.procs, got, test.depth)
+        }
+    }
+}
+
+//
+// Benchmarks for the merge sorts. Run them like this:
+//
+//    $ go test -bench=MergeSort -cpu=1,2,4,8 sort.go sort_test.go
+//
+// -cpu runs each benchmark once with GOMAXPROCS set to each of the values,
+// to see how the parallel version speeds up. With more than there are CPUs,
+// it can't speed up any more.
+//
+func benchmarkSort(b *testing.B, sort func([]int)) {
+    input := randomInts(1000000, 1000000, 1)
+    a := make([]int, len(input))
+    for i := 0; i < b.N; i++ {
+        copy(a, input)
+        sort(a)
+    }
+}
+
+func BenchmarkSequentialMergeSort(b *testing.B) {
+    benchmarkSort(b, func(a []int) { sequentialMergeSort(a) })
+}
+
+func BenchmarkParallelMergeSort(b *testing.B) {
+    benchmarkSort(b, func(a []int) { parallelMergeSort(a) })
+}
+
+func BenchmarkSortInts(b *testing.B) {
+    benchmarkSort(b, sort.Ints)
+}
+
+//
+// Values at the ends of the int range, e.g. for radix sort's sign bit
+// trick. Counting sort would need a count for every int, so it has to fall
+// back to radix sort.
+//
+func TestSortExtremes(t *testing.T) {
+    input := []int{math.MaxInt, -1, math.MinInt, 0, math.MinInt, 1}
+    for name, sort := range allSorts() {
+        a := slices.Clone(input)
+        sort(a)
+        checkSorted(t, name, input, a)
+    }
+
+    //
+    // Counting sort only falls back when the range is too big for the
+    // number of values.
+    //
+    var st sortStats
+    countingSort([]int{200, 0, 200, 0}, &st)
+    if st != (sortStats{moves: 4}) {
+        t.Errorf("counting sort of a small range: %+v", st)
+    }
+    st = sortStats{}
+    countingSort([]int{1 << 40, 0, 1 << 40, 0}, &st)
+    if st.moves <= 4 {
+        t.Errorf("counting sort of a big range didn't use radix sort: %+v", st)
+    }
+}
+
+func TestSortGeneric(t *testing.T) {
+    words := []string{"pear", "apple", "fig", "banana"}
+    insertionSort(words)
+    if !testEq(words, []string{"apple", "banana", "fig", "pear"}) || !isSorted(words) {
+        t.Errorf("insertionSort(strings) = %q", words)
+    }
+    floats := []float64{2.5, -1, 0, 1e10, -1e-10}
+    insertionSort(floats)
+    if !isSorted(floats) {
+        t.Errorf("insertionSort(floats) = %v", floats)
+    }
+    if isSorted([]string{"b", "a"}) || !isSorted([]string{}) {
+        t.Errorf("isSorted is wrong")
+    }
+
+    //
+    // Sorting by length, longest first. Words of the same length keep their
+    // order, since insertionSortFunc is stable.
+    //
+    longestFirst := func(x, y string) bool { return len(x) > len(y) }
+    words = []string{"fig", "pear", "kiwi", "apple", "date", "yam"}
+    insertionSortFunc(words, longestFirst)
+    if !testEq(words, []string{"apple", "pear", "kiwi", "date", "fig", "yam"}) ||
+       !isSortedFunc(words, longestFirst) {
+        t.Errorf("insertionSortFunc(longestFirst) = %q", words)
+    }
+    if isSortedFunc([]string{"a", "bb"}, longestFirst) {
+        t.Errorf("isSortedFunc is wrong")
+    }
+}
+
+func TestSortStats(t *testing.T) {
+    sorted := []int{1, 2, 3, 4, 5}
+    var st sortStats
+    insertionSortCounted(sorted, statsOps(&st))
+    if st != (sortStats{comparisons: 4}) {
+        t.Errorf("insertion sort of sorted input: %+v", st)
+    }
+    st = sortStats{}
+    countingSort([]int{3, 1, 2}, &st)
+    if st != (sortStats{moves: 3}) {
+        t.Errorf("counting sort: %+v", st)
+    }
+    st = sortStats{}
+    timSort(randomInts(100, 100, 1), statsOps(&st))
+    if st.comparisons == 0 {
+        t.Errorf("timsort counted no comparisons")
+    }
+}
+
+//
+// The names of every algorithm traceAlgorithm knows.
+//
+func traceableNames() []string {
+    var names []string
+    for _, alg := range sortAlgorithms {
+        names = append(names, alg.name)
+    }
+    for _, alg := range recordedSorts {
+        names = append(names, alg.name)
+    }
+    return names
+}
+
+//
+// The trace of every algorithm must record every step the sortStats
+// counted, and its last frame must be the sorted slice. The merge sorts
+// insertion sort anything up to insertionCutoff long, so they're traced on
+// a longer slice too, to see them merge.
+//
+func TestTrace(t *testing.T) {
+    type traceTest struct {
+        name  string
+        input []int
+    }
+    var tests []traceTest
+    for _, name := range traceableNames() {
+        tests = append(tests, traceTest{name, randomInts(20, 15, 7)})
+    }
+    for _, name := range []string{"sequentialMergeSort", "parallelMergeSort"} {
+        tests = append(tests, traceTest{name + " with merges", randomInts(3*insertionCutoff, 100, 8)})
+    }
+    for _, test := range tests {
+        input, expected := test.input, slices.Sorted(slices.Values(test.input))
+        name, _, _ := strings.Cut(test.name, " ")
+        tr, st := traceAlgorithm(name, input)
+        counts := map[string]int{}
+        for _, step := range tr.steps {
+            counts[step.op]++
+        }
+        if counts["compare"] != st.comparisons || counts["swap"] != st.swaps ||
+           counts["move"] != st.moves {
+            t.Errorf("%v: trace has %v, stats are %+v", test.name, counts, st)
+        }
+        if strings.HasSuffix(test.name, "with merges") && st.moves == 0 {
+            t.Errorf("%v: no moves, so it didn't merge", test.name)
+        }
+        frames := tr.frames()
+        if !testEq(frames[0].state, input) || !testEq(frames[len(frames)-1].state, expected) {
+            t.Errorf("%v: the trace doesn't go from the input to the sorted slice", test.name)
+        }
+
+        var sb strings.Builder
+        tr.replay(&sb, 0)
+        if n := strings.Count(sb.String(), "frame "); n != len(frames) {
+            t.Errorf("%v: replay showed %v frames, expected %v", test.name, n, len(frames))
+        }
+
+        sb.Reset()
+        if err := tr.writeSVG(&sb, time.Second); err != nil {
+            t.Errorf("%v: writeSVG: %v", test.name, err)
+        }
+        decoder := xml.NewDecoder(strings.NewReader(sb.String()))
+        rects := 0
+        for {
+            token, err := decoder.Token()
+            if err == io.EOF {
+                break
+            } else if err != nil {
+                t.Errorf("%v: the SVG isn't valid XML: %v", test.name, err)
+                break
+            }
+            if start, ok := token.(xml.StartElement); ok && start.Name.Local == "rect" {
+                rects++
+            }
+        }
+        if rects != len(input) {
+            t.Errorf("%v: the SVG has %v bars, expected %v", test.name, rects, len(input))
+        }
+    }
+    if tr, _ := traceAlgorithm("bogo", []int{1}); tr != nil {
+        t.Errorf("traceAlgorithm(bogo) should return nil")
+    }
+}
+
+//
+// A frame of a short trace, drawn exactly.
+//
+func TestTraceRender(t *testing.T) {
+    tr, _ := traceAlgorithm("insertion", []int{2, 1, 3})
+    var sb strings.Builder
+    tr.replay(&sb, 0)
+    expected := "frame 1/2\n" +
+                "        #\n" +
+                "  #     #\n" +
+                "  #  #  #\n" +
+                "  2  1  3\n" +
+                "start\n\n" +
+                "frame 2/2\n" +
+                "        #\n" +
+                "     @  #\n" +
+                "  @  @  #\n" +
+                "  1  2  3\n" +
+                "swap a[1] and a[0] (1 comparisons so far)\n\n"
+    if sb.String() != expected {
+        t.Errorf("replay wrote\n%v\nexpected\n%v", sb.String(), expected)
+    }
+}
+
+//
+// An empty slice has nothing to draw, but mustn't panic.
+//
+func TestTraceEmpty(t *testing.T) {
+    for _, name := range traceableNames() {
+        tr, _ := traceAlgorithm(name, []int{})
+        var sb strings.Builder
+        tr.replay(&sb, 0)
+        if expected := "frame 1/1\n\nstart\n\n"; sb.String() != expected {
+            t.Errorf("%v: replay wrote %q, expected %q", name, sb.String(), expected)
+        }
+        sb.Reset()
+        if err := tr.writeSVG(&sb, time.Second); err != nil || !strings.HasPrefix(sb.String(), "<svg") {
+            t.Errorf("%v: writeSVG wrote %q, %v", name, sb.String(), err)
+        }
+    }
+
+    //
+    // Zeros still get bars.
+    //
+    f := traceFrame{[]int{0, 0}, []int{1}, "zeros"}
+    if got, expected := f.render(15), "  #  @\n  0  0\nzeros\n"; got != expected {
+        t.Errorf("render of zeros gave %q, expected %q", got, expected)
+    }
+}
+
+//
+// Records the steps a sort takes with a sortRecorder, and replays the swaps
+// and moves on a copy of the input. The copy must end up sorted too. The
+// counts are behind a mutex, since parallelMergeSort records from several
+// goroutines.
+//
+func TestRecorder(t *testing.T) {
+    record := func(sort func(a []int, r *sortRecorder), input []int) (result []int, compares, swaps, moves int) {
+        a := slices.Clone(input)
+        replay, saved := slices.Clone(input), make([]int, len(input))
+        var mu sync.Mutex
+        sort(a, &sortRecorder{
+            compare: func(i, j int) {
+                mu.Lock()
+                defer mu.Unlock()
+                compares++
+            },
+            swap: func(i, j int) {
+                mu.Lock()
+                defer mu.Unlock()
+                swaps++
+                replay[i], replay[j] = replay[j], replay[i]
+            },
+            save: func(lo, hi int) {
+                copy(saved[lo:hi], replay[lo:hi])
+            },
+            move: func(i, from int) {
+                mu.Lock()
+                defer mu.Unlock()
+                moves++
+                replay[i] = saved[from]
+            },
+        })
+        if !testEq(a, replay) {
+            t.Errorf("replaying the recorded steps gave %v, expected %v", replay, a)
+        }
+        return a, compares, swaps, moves
+    }
+
+    // Insertion sort of 3 2 1 swaps every pair, and compares each pair once.
+    a, compares, swaps, moves := record(func(a []int, r *sortRecorder) { insertionSort(a, r) },
+                                        []int{3, 2, 1})
+    if !testEq(a, []int{1, 2, 3}) || compares != 3 || swaps != 3 || moves != 0 {
+        t.Errorf("insertionSort: %v, %v compares, %v swaps, %v moves", a, compares, swaps, moves)
+    }
+    greater := func(x, y int) bool { return x > y }
+    a, compares, swaps, moves = record(func(a []int, r *sortRecorder) { insertionSortFunc(a, greater, r) },
+                                       []int{1, 2, 3})
+    if !testEq(a, []int{3, 2, 1}) || compares != 3 || swaps != 3 || moves != 0 {
+        t.Errorf("insertionSortFunc: %v, %v compares, %v swaps, %v moves", a, compares, swaps, moves)
+    }
+
+    // Merge sort insertion sorts short runs, and merges them with moves.
+    sorts := []struct {
+        name string
+        sort func(a []int, r *sortRecorder)
+    }{
+        {"sequentialMergeSort", func(a []int, r *sortRecorder) { sequentialMergeSort(a, r) }},
+        {"parallelMergeSort", func(a []int, r *sortRecorder) { parallelMergeSort(a, r) }},
+        {"parallelSortRange", func(a []int, r *sortRecorder) {
+            parallelSortRange(a, make([]int, len(a)), 3, r) // uses goroutines even if GOMAXPROCS is 1
+        }},
+    }
+    for _, test := range sorts {
+        for _, n := range []int{0, 1, 100, 4*parallelCutoff + 5} {
+            input := randomInts(n, n, uint64(n))
+            a, compares, swaps, moves = record(test.sort, input)
+            if !isSorted(a) || checkPermutation(input, a) != nil {
+                t.Errorf("%v of %v ints is wrong", test.name, n)
+            }
+            if n > insertionCutoff && (compares == 0 || swaps == 0 || moves == 0) {
+                t.Errorf("%v of %v ints: %v compares, %v swaps, %v moves",
+                         test.name, n, compares, swaps, moves)
+            }
+        }
+    }
+
+    // The recorder is optional, and can have nil functions.
+    a = []int{2, 1}
+    insertionSort(a, &sortRecorder{})
+    sequentialMergeSort(a, nil)
+    if !testEq(a, []int{1, 2}) {
+        t.Errorf("sorting with an empty recorder gave %v", a)
+    }
+}
+
+func TestVerify(t *testing.T) {
+    intLess := func(x, y int) bool { return x < y }
+    inputs := slices.Clone(sortTests)
+    for n := 1; n <= 1000; n *= 10 {
+        inputs = append(inputs, randomInts(n, n, uint64(n)), randomInts(n, 3, uint64(n)))
+    }
+    for name, sort := range allSorts() {
+        for _, input := range inputs {
+            if err := verifySort(sort, input, intLess); err != nil {
+                t.Errorf("%v: %v", name, err)
+            }
+        }
+    }
+
+    //
+    // Each check must catch a sort that's wrong in the way it checks for.
+    //
+    zeros := func(a []int) {
+        for i := range a {
+            a[i] = 0
+        }
+    }
+    if err := verifySort(zeros, []int{3, 1, 2}, intLess); err == nil {
+        t.Errorf("verifySort missed a sort that loses values")
+    }
+    if err := checkPermutation([]int{1, 2, 2}, []int{1, 1, 2}); err == nil {
+        t.Errorf("checkPermutation missed a changed value")
+    }
+    if err := checkPermutation([]string{"a"}, []string{"a", "a"}); err == nil {
+        t.Errorf("checkPermutation missed a changed length")
+    }
+    if err := checkOrder([]int{1, 3, 2}, intLess); err == nil {
+        t.Errorf("checkOrder missed an unsorted slice")
+    }
+
+    // Sorts, but reverses a slice that's already sorted.
+    fickle := func(a []int) {
+        if isSorted(a) {
+            slices.Reverse(a)
+        } else {
+            slices.Sort(a)
+        }
+    }
+    if err := checkIdempotent(fickle, []int{3, 1, 2}); err == nil {
+        t.Errorf("checkIdempotent missed a sort that changes sorted input")
+    }
+    if err := checkIdempotent(func(a []int) { insertionSort(a) }, []int{3, 1, 2}); err != nil {
+        t.Errorf("checkIdempotent: %v", err)
+    }
+}
+
+func TestCheckStable(t *testing.T) {
+    byLength := func(x, y string) bool { return len(x) < len(y) }
+    taggedLess := func(a, b tagged[string]) bool { return byLength(a.value, b.value) }
+    input := []string{"bb", "cc", "a", "ccc", "b", "aaa", "c"}
+
+    stable := map[string]func([]tagged[string]){
+        "insertionSortFunc": func(a []tagged[string]) { insertionSortFunc(a, taggedLess) },
+        "slices.SortStableFunc": func(a []tagged[string]) {
+            slices.SortStableFunc(a, func(x, y tagged[string]) int {
+                return len(x.value) - len(y.value)
+            })
+        },
+        "sort.SliceStable": func(a []tagged[string]) {
+            sort.SliceStable(a, func(i, j int) bool { return taggedLess(a[i], a[j]) })
+        },
+    }
+    for name, s := range stable {
+        if err := checkStable(s, input, byLength); err != nil {
+            t.Errorf("%v: %v", name, err)
+        }
+    }
+
+    //
+    // Selection sort swaps the smallest remaining value to the front, which
+    // can jump it over values equal to the one it's swapped with.
+    //
+    selectionSort := func(a []tagged[string]) {
+        for i := range a {
+            smallest := i
+            for j := i + 1; j < len(a); j++ {
+                if taggedLess(a[j], a[smallest]) {
+                    smallest = j
+                }
+            }
+            a[i], a[smallest] = a[smallest], a[i]
+        }
+    }
+    if err := checkStable(selectionSort, input, byLength); err == nil {
+        t.Errorf("checkStable missed that selection sort isn't stable")
+    }
+}
+
+//
+// The library's own sorts, checked with the adapters. Lots of values with
+// only a few different keys make sure equal values get merged from
+// different runs, and that unstable sorts get caught.
+//
+func TestLibraryStable(t *testing.T) {
+    intLess := func(x, y int) bool { return x < y }
+    inputs := [][]int{nil, {1}, {2, 1, 2, 1}, randomInts(100, 3, 1),
+                      randomInts(1000, 10, 2), randomInts(3*parallelCutoff, 50, 3)}
+
+    stable := map[string]func([]tagged[int]){
+        "merge":     stableOps(mergeSort, intLess),
+        "tim":       stableOps(timSort, intLess),
+        "insertion": stableOps(insertionSortCounted, intLess),
+        "insertionSort": stableRecorded(func(a []int, r *sortRecorder) { insertionSort(a, r) }),
+        "insertionSortFunc": stableRecorded(func(a []int, r *sortRecorder) {
+            insertionSortFunc(a, intLess, r)
+        }),
+        "sequentialMergeSort": stableRecorded(func(a []int, r *sortRecorder) { sequentialMergeSort(a, r) }),
+        "parallelMergeSort": stableRecorded(func(a []int, r *sortRecorder) { parallelMergeSort(a, r) }),
+        "parallelSortRange": stableRecorded(func(a []int, r *sortRecorder) {
+            parallelSortRange(a, make([]int, len(a)), 3, r)
+        }),
+    }
+    for name, s := range stable {
+        for _, input := range inputs {
+            if name == "insertionSort" && len(input) > 1000 {
+                continue // too slow
+            }
+            if err := checkStable(s, input, intLess); err != nil {
+                t.Errorf("%v of %v values: %v", name, len(input), err)
+            }
+        }
+    }
+
+    //
+    // The adapters must also sort, whatever the values, and catch the sorts
+    // that aren't stable.
+    //
+    a := make([]tagged[int], 6)
+    for i, x := range []int{1 << 40, 5, 1 << 40, -3, math.MinInt, math.MaxInt} {
+        a[i].value, a[i].index = x, i
+    }
+    stable["merge"](a)
+    expected := fmt.Sprintf("[%v#4 -3#3 5#1 %v#0 %v#2 %v#5]", math.MinInt, 1<<40, 1<<40, math.MaxInt)
+    if got := fmt.Sprint(a); got != expected {
+        t.Errorf("stableOps(mergeSort) gave %v, expected %v", got, expected)
+    }
+    unstable := map[string]func([]tagged[int]){
+        "quick": stableOps(quickSort, intLess),
+        "heap":  stableOps(heapSort, intLess),
+        "shell": stableOps(shellSort, intLess),
+    }
+    for name, s := range unstable {
+        if err := checkStable(s, inputs[4], intLess); err == nil {
+            t.Errorf("checkStable missed that %v sort isn't stable", name)
+        }
+    }
+}
+
+//
+// Converts fuzz input bytes to ints. Each byte is read as an int8, so there
+// are negative numbers too.
+//
+func bytesToInts(data []byte) []int {
+    a := make([]int, len(data))
+    for i, b := range data {
+        a[i] = int(int8(b))
+    }
+    return a
+}
+
+//
+// Like bytesToInts, but each byte becomes the top byte of the int, so the
+// values are spread over the whole int range, from math.MinInt up.
+//
+func bytesToWideInts(data []byte) []int {
+    a := bytesToInts(data)
+    for i := range a {
+        a[i] <<= 56
+    }
+    return a
+}
+
+func intsToBytes(a []int) []byte {
+    data := make([]byte, len(a))
+    for i, x := range a {
+        data[i] = byte(int8(x))
+    }
+    return data
+}
+
+func FuzzInsert(f *testing.F) {
+    for _, test := range insertTests {
+        f.Add(test.x, test.i, intsToBytes(test.lst))
+    }
+    f.Fuzz(func(t *testing.T, x int, i int, data []byte) {
+        lst := bytesToInts(data)
+        clamped := min(max(i, 0), len(lst))
+        expected := slices.Insert(slices.Clone(lst), clamped, x)
+        for _, ins := range inserts {
+            if result := ins.insert(x, i, lst); !testEq(result, expected) {
+                t.Errorf("%v(%v, %v, %v) = %v, expected %v", ins.name, x, i, lst, result, expected)
+            }
+            if !testEq(lst, bytesToInts(data)) {
+                t.Errorf("%v changed its input", ins.name)
+            }
+        }
+    })
+}
+
+func FuzzSort(f *testing.F) {
+    for _, input := range sortTests {
+        f.Add(intsToBytes(input))
+    }
+    f.Fuzz(func(t *testing.T, data []byte) {
+        for _, input := range [][]int{bytesToInts(data), bytesToWideInts(data)} {
+            for name, sort := range allSorts() {
+                if err := verifySort(sort, input, func(x, y int) bool { return x < y }); err != nil {
+                    t.Errorf("%v(%v): %v", name, input, err)
+                }
+            }
+        }
+    })
+}
+
+func TestBounds(t *testing.T) {
+    a := []int{1, 3, 3, 3, 5, 8}
+    tests := []struct {
+        x, lower, upper int
+    }{
+        {0, 0, 0}, {1, 0, 1}, {2, 1, 1}, {3, 1, 4}, {4, 4, 4},
+        {5, 4, 5}, {8, 5, 6}, {9, 6, 6},
+    }
+    for _, test := range tests {
+        if got := lowerBound(a, test.x); got != test.lower {
+            t.Errorf("lowerBound(%v, %v) = %v, expected %v", a, test.x, got, test.lower)
+        }
+        if got := upperBound(a, test.x); got != test.upper {
+            t.Errorf("upperBound(%v, %v) = %v, expected %v", a, test.x, got, test.upper)
+        }
+    }
+    if lowerBound([]string{}, "x") != 0 || upperBound([]string{}, "x") != 0 {
+        t.Errorf("bounds of an empty slice should be 0")
+    }
+}
+
+func TestMergeSorted(t *testing.T) {
+    tests := []struct {
+        a, b, expected []int
+    }{
+        {nil, nil, []int{}},
+        {[]int{1, 2}, nil, []int{1, 2}},
+        {nil, []int{1, 2}, []int{1, 2}},
+        {[]int{1, 4, 6}, []int{2, 4, 5, 9}, []int{1, 2, 4, 4, 5, 6, 9}},
+    }
+    for _, test := range tests {
+        if got := mergeSorted(test.a, test.b); !testEq(got, test.expected) {
+            t.Errorf("mergeSorted(%v, %v) = %v, expected %v", test.a, test.b, got, test.expected)
+        }
+    }
+}
+
+func TestSortedSlice(t *testing.T) {
+    var s SortedSlice[string] // the zero value is ready to use
+    for _, w := range []string{"pear", "apple", "fig", "apple", "kiwi"} {
+        s.Insert(w)
+    }
+    if !testEq(s.Values(), []string{"apple", "apple", "fig", "kiwi", "pear"}) {
+        t.Errorf("Insert: got %v", s.Values())
+    }
+    if !s.Contains("fig") || s.Contains("banana") || s.Contains("zebra") {
+        t.Errorf("Contains is wrong")
+    }
+    if s.Rank("apple") != 0 || s.Rank("banana") != 2 || s.Rank("kiwi") != 3 || s.Rank("z") != 5 {
+        t.Errorf("Rank is wrong")
+    }
+    if got := s.Range("b", "l"); !testEq(got, []string{"fig", "kiwi"}) {
+        t.Errorf("Range(b, l) = %v", got)
+    }
+    if got := s.Range("l", "b"); len(got) != 0 {
+        t.Errorf("Range(l, b) = %v, expected []", got)
+    }
+    if !s.Remove("apple") || !testEq(s.Values(), []string{"apple", "fig", "kiwi", "pear"}) {
+        t.Errorf("Remove(apple): got %v", s.Values())
+    }
+    if s.Remove("banana") || s.Len() != 4 {
+        t.Errorf("Remove(banana) should do nothing")
+    }
+    if s.At(0) != "apple" || s.At(3) != "pear" {
+        t.Errorf("At is wrong")
+    }
+
+    values := []int{5, 1, 4}
+    a, b := NewSortedSlice(values...), NewSortedSlice(4, 2)
+    if !testEq(values, []int{5, 1, 4}) {
+        t.Errorf("NewSortedSlice changed its input")
+    }
+    if m := a.Merge(b); !testEq(m.Values(), []int{1, 2, 4, 4, 5}) || a.Len() != 3 {
+        t.Errorf("Merge: got %v", m)
+    }
+}
+
+//
+// Does random inserts and removes on a SortedSlice, and checks it against a
+// plain slice that's sorted after each change. A non-negative byte is
+// inserted, and a negative one means remove -b.
+//
+func FuzzSortedSlice(f *testing.F) {
+    f.Add([]byte{3, 1, 2, 0xfe, 2, 0xfd})
+    f.Add([]byte{1, 1, 1, 0xff, 0xff, 0xff, 0xff})
+    f.Fuzz(func(t *testing.T, data []byte) {
+        var s SortedSlice[int]
+        var model []int
+        for _, x := range bytesToInts(data) {
+            if x >= 0 {
+                s.Insert(x)
+                model = append(model, x)
+                slices.Sort(model)
+            } else {
+                i := slices.Index(model, -x)
+                if removed := s.Remove(-x); removed != (i >= 0) {
+                    t.Fatalf("Remove(%v) = %v", -x, removed)
+                }
+                if i >= 0 {
+                    model = slices.Delete(model, i, i+1)
+                }
+            }
+            if !testEq(s.Values(), model) {
+                t.Fatalf("got %v, expected %v", s.Values(), model)
+            }
+        }
+        for x := -1; x <= 128; x++ {
+            if s.Contains(x) != slices.Contains(model, x) {
+                t.Errorf("Contains(%v) is wrong", x)
+            }
+            lo, hi := x, x+10
+            expected := slices.DeleteFunc(slices.Clone(model), func(v int) bool {
+                return v < lo || v >= hi
+            })
+            if got := s.Range(lo, hi); !testEq(got, expected) {
+                t.Errorf("Range(%v, %v) = %v, expected %v", lo, hi, got, expected)
+            }
+        }
+    })
+}
@@ -0,0 +1,94 @@
+// main.go
+
+//
+// Uses the partial sorting functions to find the most common words in Pride
+// and Prejudice, timing them against sorting all the words. go run can't be
+// given the _test.go files, so run it like this:
+//
+//    $ go run $(ls *.go | grep -v _test.go)
+//
+// The tests are in topk_test.go. Run them like this:
+//
+//    $ go test *.go
+//
+
+package main
+
+import (
+    "fmt"
+    "os"
+    "regexp"
+    "sort"
+    "strings"
+    "time"
+)
+
+func main() {
+    bytes, err := os.ReadFile("../austenPandP.txt")
+    if err != nil {
+        fmt.Println(err)
+        return
+    }
+    words := regexp.MustCompile(`[a-z]+`).FindAllString(strings.ToLower(string(bytes)), -1)
+    freq := map[string]int{}
+    for _, w := range words {
+        freq[w]++
+    }
+
+    type kv struct {
+        key string
+        val int
+    }
+    var arr []kv
+    for k, v := range freq {
+        arr = append(arr, kv{k, v})
+    }
+
+    //
+    // Most frequent first, and alphabetically for words with the same count,
+    // like in ../wordcount.go.
+    //
+    less := func(a, b kv) bool {
+        if a.val != b.val {
+            return a.val > b.val
+        }
+        return a.key < b.key
+    }
+
+    const k = 10
+    fmt.Printf("The %v most common of %v different words:\n", k, len(arr))
+    for i, pair := range TopK(arr, k, less) {
+        fmt.Printf("%v. %v (%v)\n", i+1, pair.key, pair.val)
+    }
+
+    //
+    // Time each way of finding the top k. Each one gets its own copy of arr,
+    // since some of them rearrange it.
+    //
+    timeIt := func(name string, f func(a []kv) []kv) {
+        const runs = 20
+        var elapsed time.Duration
+        var top []kv
+        for i := 0; i < runs; i++ {
+            a := append([]kv{}, arr...)
+            start := time.Now()
+            top = f(a)
+            elapsed += time.Since(start)
+        }
+        fmt.Printf("%-18s %10v  (top: %v)\n", name, elapsed/runs, top[0].key)
+    }
+    fmt.Println()
+    timeIt("sort.Slice", func(a []kv) []kv {
+        sort.Slice(a, func(i, j int) bool { return less(a[i], a[j]) })
+        return a[:k]
+    })
+    timeIt("TopK", func(a []kv) []kv { return TopK(a, k, less) })
+    timeIt("PartialSort", func(a []kv) []kv {
+        PartialSort(a, k, less)
+        return a[:k]
+    })
+    timeIt("PartialSortStable", func(a []kv) []kv {
+        PartialSortStable(a, k, less)
+        return a[:k]
+    })
+}
@@ -0,0 +1,304 @@
+// topk.go
+
+//
+// Partial sorting: finding the k smallest values without sorting everything.
+//
+// Sorting n values takes O(n log n) time, but if only the first k of them
+// are needed (e.g. the 10 most common words in a book), there are faster
+// ways:
+//
+// - TopK keeps the best k values seen so far in a heap of size k, which
+//   takes O(n log k) time and only O(k) extra memory. It works on a stream
+//   of values too (TopKSeq), so the values don't all have to be in memory.
+//
+// - Select uses quickselect to find the value that would be at position k
+//   if the values were sorted, in O(n) time on average.
+//
+// - PartialSort uses Select, then sorts just the first k values, which
+//   takes O(n + k log k) time on average.
+//
+// Every function takes a less function, like sort.Slice. "Smallest" means
+// "first in the order less defines", so to get the largest values, pass a
+// less that compares with >.
+//
+// Values that are equal according to less can come out in any order. The
+// Stable versions break ties by position instead, so equal values stay in
+// the order they were in the input, just like sort.SliceStable.
+//
+
+package main
+
+import (
+    "fmt"
+    "iter"
+    "slices"
+    "sort"
+)
+
+//
+// A heap of at most k values, with the largest (last in less order) value
+// at the top. A new value that's smaller than the top replaces it, so the
+// heap always holds the k smallest values seen so far.
+//
+type boundedHeap[T any] struct {
+    items []T
+    k     int
+    less  func(a, b T) bool
+}
+
+func (h *boundedHeap[T]) push(x T) {
+    if len(h.items) < h.k {
+        h.items = append(h.items, x)
+        h.siftUp(len(h.items) - 1)
+    } else if h.k > 0 && h.less(x, h.items[0]) {
+        h.items[0] = x
+        h.siftDown(0)
+    }
+}
+
+//
+// The heap is stored in a slice: the children of items[i] are items[2i+1]
+// and items[2i+2]. Each value is no smaller than its children.
+//
+func (h *boundedHeap[T]) siftUp(i int) {
+    for i > 0 {
+        parent := (i - 1) / 2
+        if !h.less(h.items[parent], h.items[i]) {
+            return
+        }
+        h.items[parent], h.items[i] = h.items[i], h.items[parent]
+        i = parent
+    }
+}
+
+func (h *boundedHeap[T]) siftDown(i int) {
+    n := len(h.items)
+    for {
+        largest := i
+        left, right := 2*i+1, 2*i+2
+        if left < n && h.less(h.items[largest], h.items[left]) {
+            largest = left
+        }
+        if right < n && h.less(h.items[largest], h.items[right]) {
+            largest = right
+        }
+        if largest == i {
+            return
+        }
+        h.items[i], h.items[largest] = h.items[largest], h.items[i]
+        i = largest
+    }
+}
+
+//
+// Returns the values in the heap in sorted order.
+//
+func (h *boundedHeap[T]) sorted() []T {
+    result := h.items
+    sort.Slice(result, func(i, j int) bool { return h.less(result[i], result[j]) })
+    return result
+}
+
+//
+// Returns the k smallest values from seq, in sorted order. If seq has fewer
+// than k values, all of them are returned.
+//
+func TopKSeq[T any](seq iter.Seq[T], k int, less func(a, b T) bool) []T {
+    h := &boundedHeap[T]{k: max(k, 0), less: less}
+    for x := range seq {
+        h.push(x)
+    }
+    return h.sorted()
+}
+
+//
+// Returns the k smallest values in items, in sorted order. items isn't
+// changed.
+//
+func TopK[T any](items []T, k int, less func(a, b T) bool) []T {
+    return TopKSeq(slices.Values(items), k, less)
+}
+
+//
+// A value tagged with its position in the input. Comparing tagged values
+// with stableLess breaks ties by position, which makes any algorithm
+// stable.
+//
+type tagged[T any] struct {
+    index int
+    value T
+}
+
+func stableLess[T any](less func(a, b T) bool) func(a, b tagged[T]) bool {
+    return func(a, b tagged[T]) bool {
+        if less(a.value, b.value) {
+            return true
+        } else if less(b.value, a.value) {
+            return false
+        }
+        return a.index < b.index
+    }
+}
+
+func untag[T any](items []tagged[T]) []T {
+    result := make([]T, len(items))
+    for i, x := range items {
+        result[i] = x.value
+    }
+    return result
+}
+
+//
+// Like TopKSeq, but values that are equal according to less are returned in
+// the order they came from seq, and when there are more equal values than
+// room, the earliest ones are kept.
+//
+func TopKSeqStable[T any](seq iter.Seq[T], k int, less func(a, b T) bool) []T {
+    tag := func(yield func(tagged[T]) bool) {
+        i := 0
+        for x := range seq {
+            if !yield(tagged[T]{i, x}) {
+                return
+            }
+            i++
+        }
+    }
+    return untag(TopKSeq(tag, k, stableLess(less)))
+}
+
+//
+// Like TopK, but stable.
+//
+func TopKStable[T any](items []T, k int, less func(a, b T) bool) []T {
+    return TopKSeqStable(slices.Values(items), k, less)
+}
+
+//
+// Rearranges items so that items[k] is the value that would be there if
+// items were sorted, every value before it is less than or equal to it, and
+// every value after it is greater than or equal to it. Returns items[k].
+// k must be a valid index.
+//
+// This is quickselect: like quicksort, it partitions items around a pivot,
+// but then only carries on with the part that k is in. Each partition is
+// three-way (less than, equal to, and greater than the pivot), so lots of
+// equal values don't slow it down.
+//
+func Select[T any](items []T, k int, less func(a, b T) bool) T {
+    if k < 0 || k >= len(items) {
+        panic(fmt.Sprintf("Select: index %v out of range [0, %v)", k, len(items)))
+    }
+    lo, hi := 0, len(items) // the part of items that k is in
+    for hi-lo > 1 {
+        pivot := medianOfThree(items[lo], items[lo+(hi-lo)/2], items[hi-1], less)
+        lt, gt := partition3(items[lo:hi], pivot, less)
+        lt, gt = lo+lt, lo+gt
+        switch {
+        case k < lt  : hi = lt
+        case k >= gt : lo = gt
+        default      : return items[k] // k is among the values equal to pivot
+        }
+    }
+    return items[k]
+}
+
+//
+// Returns the middle one of a, b and c. Using it as the pivot avoids the
+// worst case of quickselect for input that's already sorted or reversed.
+//
+func medianOfThree[T any](a, b, c T, less func(a, b T) bool) T {
+    if less(b, a) {
+        a, b = b, a
+    }
+    if less(c, b) {
+        b = c
+        if less(b, a) {
+            b = a
+        }
+    }
+    return b
+}
+
+//
+// Rearranges items into three parts: the values less than pivot in
+// items[:lt], the ones equal to pivot in items[lt:gt], and the ones greater
+// than pivot in items[gt:]. This is Dijkstra's "Dutch national flag"
+// partition.
+//
+func partition3[T any](items []T, pivot T, less func(a, b T) bool) (lt, gt int) {
+    lt, i, gt := 0, 0, len(items)
+    for i < gt {
+        switch {
+        case less(items[i], pivot):
+            items[lt], items[i] = items[i], items[lt]
+            lt++
+            i++
+        case less(pivot, items[i]):
+            gt--
+            items[i], items[gt] = items[gt], items[i]
+        default:
+            i++
+        }
+    }
+    return lt, gt
+}
+
+//
+// Rearranges items so that items[:k] are its k smallest values, in sorted
+// order. The rest of items is in no particular order. If k >= len(items),
+// all of items is sorted.
+//
+func PartialSort[T any](items []T, k int, less func(a, b T) bool) {
+    k = min(max(k, 0), len(items))
+    if k == 0 {
+        return
+    }
+    if k < len(items) {
+        Select(items, k-1, less)
+    }
+    first := items[:k]
+    sort.Slice(first, func(i, j int) bool { return less(first[i], first[j]) })
+}
+
+//
+// Like PartialSort, but stable: items[:k] are the k smallest values in the
+// order sort.SliceStable would put them, and items[k:] are the rest of the
+// values in the order they were in before.
+//
+func PartialSortStable[T any](items []T, k int, less func(a, b T) bool) {
+    t := make([]tagged[T], len(items))
+    for i, x := range items {
+        t[i] = tagged[T]{i, x}
+    }
+    PartialSort(t, k, stableLess(less))
+    k = min(max(k, 0), len(items))
+
+    //
+    // Put the values that weren't chosen back after the chosen ones, in
+    // their original order.
+    //
+    chosen := make([]bool, len(items))
+    for _, x := range t[:k] {
+        chosen[x.index] = true
+    }
+    rest := make([]T, 0, len(items)-k)
+    for i, x := range items {
+        if !chosen[i] {
+            rest = append(rest, x)
+        }
+    }
+    copy(items, untag(t[:k]))
+    copy(items[k:], rest)
+}
+
+//
+// Like Select, but returns the value at position k in stable sorted order.
+// items is rearranged as PartialSortStable(items, k+1, less) does.
+//
+func SelectStable[T any](items []T, k int, less func(a, b T) bool) T {
+    if k < 0 || k >= len(items) {
+        panic(fmt.Sprintf("SelectStable: index %v out of range [0, %v)", k, len(items)))
+    }
+    PartialSortStable(items, k+1, less)
+    return items[k]
+}
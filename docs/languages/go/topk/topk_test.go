@@ -0,0 +1,169 @@
+// topk_test.go
+
+//
+// Tests for the partial sorting functions. Each one is compared against
+// sorting everything with sort.SliceStable. Run them like this:
+//
+//    $ go test *.go
+//
+
+package main
+
+import (
+    "fmt"
+    "slices"
+    "sort"
+    "testing"
+)
+
+//
+// Pairs with the same word compare as equal with byWord, so the stable
+// versions must keep them in input order.
+//
+type pair struct {
+    word string
+    n    int
+}
+
+func byWord(a, b pair) bool { return a.word < b.word }
+func byN(a, b pair) bool    { return a.n < b.n }
+func intLess(a, b int) bool { return a < b }
+
+//
+// Returns a sorted copy of items.
+//
+func sortedCopy[T any](items []T, less func(a, b T) bool) []T {
+    result := slices.Clone(items)
+    sort.SliceStable(result, func(i, j int) bool { return less(result[i], result[j]) })
+    return result
+}
+
+//
+// Returns just the n values of ps. Only these are compared for the unstable
+// versions.
+//
+func ns(ps []pair) []int {
+    var result []int
+    for _, p := range ps {
+        result = append(result, p.n)
+    }
+    return result
+}
+
+func TestTopK(t *testing.T) {
+    tests := []struct {
+        name  string
+        items []int
+        k     int
+        less  func(a, b int) bool
+        want  []int
+    }{
+        {"TopK", []int{5, 1, 4, 2, 3}, 3, intLess, []int{1, 2, 3}},
+        {"TopK largest", []int{5, 1, 4, 2, 3}, 2, func(a, b int) bool { return a > b }, []int{5, 4}},
+        {"TopK k > n", []int{2, 1}, 5, intLess, []int{1, 2}},
+        {"TopK k = 0", []int{2, 1}, 0, intLess, nil},
+        {"TopK k < 0", []int{2, 1}, -1, intLess, nil},
+        {"TopK empty", []int{}, 3, intLess, nil},
+    }
+    for _, tc := range tests {
+        if got := TopK(tc.items, tc.k, tc.less); !slices.Equal(got, tc.want) {
+            t.Errorf("%v: TopK(%v, %v) = %v, expected %v", tc.name, tc.items, tc.k, got, tc.want)
+        }
+    }
+
+    items := []int{3, 1, 2}
+    TopK(items, 2, intLess)
+    if !slices.Equal(items, []int{3, 1, 2}) {
+        t.Errorf("TopK changed its input to %v", items)
+    }
+    if got := Select([]int{5, 1, 4, 2, 3}, 1, intLess); got != 2 {
+        t.Errorf("Select([5 1 4 2 3], 1) = %v, expected 2", got)
+    }
+    if got := Select([]int{7}, 0, intLess); got != 7 {
+        t.Errorf("Select([7], 0) = %v, expected 7", got)
+    }
+}
+
+func TestStable(t *testing.T) {
+    pairs := []pair{{"b", 1}, {"a", 2}, {"b", 3}, {"a", 4}, {"c", 5}, {"a", 6}}
+    tests := []struct {
+        name string
+        got  any
+        want any
+    }{
+        {"TopKStable", TopKStable(pairs, 4, byWord), []pair{{"a", 2}, {"a", 4}, {"a", 6}, {"b", 1}}},
+        {"TopKStable keeps earliest", TopKStable(pairs, 2, byWord), []pair{{"a", 2}, {"a", 4}}},
+        {"PartialSortStable", func() []pair {
+            p := slices.Clone(pairs)
+            PartialSortStable(p, 3, byWord)
+            return p
+        }(), []pair{{"a", 2}, {"a", 4}, {"a", 6}, {"b", 1}, {"b", 3}, {"c", 5}}},
+        {"SelectStable", SelectStable(slices.Clone(pairs), 4, byWord), pair{"b", 3}},
+    }
+    for _, tc := range tests {
+        if fmt.Sprint(tc.got) != fmt.Sprint(tc.want) {
+            t.Errorf("%v: got %v, expected %v", tc.name, tc.got, tc.want)
+        }
+    }
+}
+
+//
+// Random tests: for lots of random inputs and every k, compare with a full
+// stable sort. Values are in 0..9 with up to 20 of them, so there are plenty
+// of ties.
+//
+func TestRandom(t *testing.T) {
+    seed := uint32(1)
+    random := func(n int) int {
+        seed = seed*1664525 + 1013904223
+        return int(seed>>16) % n
+    }
+    for trial := 0; trial < 200; trial++ {
+        n := random(20) + 1
+        input := make([]pair, n)
+        for i := range input {
+            input[i] = pair{fmt.Sprint(i), random(10)}
+        }
+        sorted := sortedCopy(input, byN)
+
+        for k := 0; k <= n; k++ {
+            if got := TopK(input, k, byN); !slices.Equal(ns(got), ns(sorted[:k])) {
+                t.Errorf("TopK(%v, %v) = %v", input, k, got)
+            }
+            if got := TopKStable(input, k, byN); !slices.Equal(got, sorted[:k]) {
+                t.Errorf("TopKStable(%v, %v) = %v", input, k, got)
+            }
+
+            p := slices.Clone(input)
+            PartialSort(p, k, byN)
+            if !slices.Equal(ns(p[:k]), ns(sorted[:k])) || !slices.Equal(ns(sortedCopy(p, byN)), ns(sorted)) {
+                t.Errorf("PartialSort(%v, %v) gave %v", input, k, p)
+            }
+
+            p = slices.Clone(input)
+            PartialSortStable(p, k, byN)
+            rest := slices.DeleteFunc(slices.Clone(input), func(x pair) bool {
+                return slices.Contains(sorted[:k], x)
+            })
+            if !slices.Equal(p, append(slices.Clone(sorted[:k]), rest...)) {
+                t.Errorf("PartialSortStable(%v, %v) gave %v", input, k, p)
+            }
+
+            if k < n {
+                p = slices.Clone(input)
+                x := Select(p, k, byN)
+                ok := x.n == sorted[k].n && p[k] == x
+                for i := range p {
+                    ok = ok && (i >= k || !byN(x, p[i])) && (i <= k || !byN(p[i], x))
+                }
+                if !ok {
+                    t.Errorf("Select(%v, %v) = %v, leaving %v", input, k, x, p)
+                }
+                p = slices.Clone(input)
+                if got := SelectStable(p, k, byN); got != sorted[k] {
+                    t.Errorf("SelectStable(%v, %v) = %v, expected %v", input, k, got, sorted[k])
+                }
+            }
+        }
+    }
+}
@@ -10,33 +10,183 @@
 // Convert all uppercase letters A-Z to lowercase a-z, and replace characters
 // that are not a-z with spaces.
 //
+// It uses the Unicode functions in accents.go, so run it like this:
+//
+//    $ go run wordcount.go accents.go austenPandP.txt
+//    $ go run wordcount.go accents.go -n 10 austenPandP.txt short.txt
+//    $ cat short.txt | go run wordcount.go accents.go -n 5
+//
+// The tests are in wordcount_test.go. Run them like this:
+//
+//    $ go test wordcount.go accents.go wordcount_test.go
+//
+// With several files, the words in all of them are counted together. With
+// no files, or a file named "-", it reads standard input.
+//
+// Only treating a-z as letters mangles words like "café" and "naïve", and
+// any text that isn't in English. With -tokenizer=unicode, a word is any run
+// of Unicode letters instead, e.g.
+//
+//    $ go run wordcount.go accents.go -tokenizer=unicode -apostrophes -hyphens file.txt
+//
+// -apostrophes keeps contractions like "don't" as one word, and -hyphens
+// does the same for words like "well-known".
+//
+// The unicode tokenizer normalizes the text to NFC (see accents.go), so
+// "café" is one word however its é is written. -nfc=false turns that off,
+// which shows whether a text mixes the two ways of writing accents: the
+// same word then appears twice in the list.
+//
 
 package main
 
 import (
+    "flag"
     "fmt"
-    "io/ioutil"
+    "io"
+    "os"
     "regexp"
     "sort"
     "strings"
+    "unicode"
 )
 
+//
+// A key-value struct for sorting the frequencies.
+//
+type kv struct {
+    key string // a word
+    val int    // number of times the word occurs
+}
+
 func main() {
+    os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+//
+// Runs wordcount with the command-line arguments args (not including the
+// program name), and returns the exit status: 0 if it worked, 1 if a file
+// couldn't be read, and 2 if the arguments are wrong.
+//
+// Taking the arguments, standard input and output as parameters, rather
+// than using os.Args, os.Stdin and os.Stdout directly, lets the tests in
+// wordcount_test.go run it and check what it prints.
+//
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+    //
+    // The flag package parses command-line options like -n 10. flags.Int
+    // returns a pointer to an int that flags.Parse sets. A FlagSet is a set
+    // of flags; flag.Int and flag.Parse use one for os.Args, but this one
+    // parses args.
+    //
+    flags := flag.NewFlagSet("wordcount", flag.ContinueOnError)
+    flags.SetOutput(stderr)
+    N := flags.Int("n", 100, "number of words to print (0 prints them all)")
+    tokenizer := flags.String("tokenizer", "ascii", "how to find words: ascii (a-z only) or unicode")
+    var opts tokenizerOptions
+    flags.BoolVar(&opts.apostrophes, "apostrophes", false,
+                  "with -tokenizer=unicode, keep apostrophes inside words, e.g. don't")
+    flags.BoolVar(&opts.hyphens, "hyphens", false,
+                  "with -tokenizer=unicode, keep hyphens inside words, e.g. well-known")
+    flags.BoolVar(&opts.nfc, "nfc", true,
+                  "with -tokenizer=unicode, normalize accents to NFC so e.g. café is one word")
+    flags.Usage = func() {
+        fmt.Fprintln(stderr, "usage: wordcount [-n N] [-tokenizer ascii|unicode] [file ...]")
+        flags.PrintDefaults()
+    }
+    if err := flags.Parse(args); err == flag.ErrHelp {
+        return 0
+    } else if err != nil {
+        return 2 // flags.Parse has already printed the error and the usage
+    }
+    if *N < 0 {
+        fmt.Fprintln(stderr, "wordcount: -n must be 0 or more")
+        return 2
+    }
+
+    //
+    // splitWords is a function variable, set to whichever tokenizer was
+    // asked for.
+    //
+    var splitWords func(string) []string
+    switch *tokenizer {
+    case "ascii":
+        if opts.apostrophes || opts.hyphens || !opts.nfc {
+            fmt.Fprintln(stderr, "wordcount: -apostrophes, -hyphens and -nfc need -tokenizer=unicode")
+            return 2
+        }
+        splitWords = asciiWords
+    case "unicode":
+        splitWords = func(content string) []string {
+            return unicodeWords(content, opts)
+        }
+    default:
+        fmt.Fprintf(stderr, "wordcount: unknown tokenizer %q (use ascii or unicode)\n", *tokenizer)
+        return 2
+    }
+
+    //
+    // The files are the arguments left after the flags.
+    //
+    fnames := flags.Args()
+    if len(fnames) == 0 {
+        fnames = []string{"-"}
+    }
+
+    freq := map[string]int{}
+    for _, fname := range fnames {
+        content, err := readFile(fname, stdin)
+        if err != nil {
+            //
+            // Print a clear message and exit with a non-zero status, rather
+            // than panic. err already says which file and what went wrong,
+            // e.g. "open nosuch.txt: no such file or directory".
+            //
+            fmt.Fprintln(stderr, "wordcount:", err)
+            return 1
+        }
+        countWords(splitWords(content), freq)
+    }
+
+    top := topWords(freq, *N)
+    if len(top) == 0 {
+        fmt.Fprintln(stdout, "no words found")
+    }
+    for i, pair := range top {
+        fmt.Fprintf(stdout, "%v. %v (%v)\n", i + 1, pair.key, pair.val)
+    }
+    return 0
+} // run
+
+//
+// Returns the contents of the file named fname, or of stdin (usually
+// os.Stdin) if fname is "-".
+//
+func readFile(fname string, stdin io.Reader) (string, error) {
     //
-    // Open the file. ioutil.ReadFile is a utility function that reads all the
-    // lines of a file in a slice of bytes.
+    // os.ReadFile is a utility function that reads all the lines of a file in
+    // a slice of bytes. io.ReadAll does the same for any io.Reader, like
+    // os.Stdin.
     //
-    fname := "austenPandP.txt"
-    // fname := "short.txt"
-    bytes, err := ioutil.ReadFile(fname)
-    if err != nil {
-        panic("Couldn't open file!")
+    var bytes []byte
+    var err error
+    if fname == "-" {
+        bytes, err = io.ReadAll(stdin)
+    } else {
+        bytes, err = os.ReadFile(fname)
     }
+    return string(bytes), err
+}
 
+//
+// Returns the words in content, converted to lowercase. Only the letters a-z
+// count as letters.
+//
+func asciiWords(content string) []string {
     //
     // Convert letters to lowercase, non-letters to spaces.
     //
-    content := strings.ToLower(string(bytes))
+    content = strings.ToLower(content)
 
     // These two lines convert all non-lowercase letters to spaces.
     rep := regexp.MustCompile(`[^a-z]`)
@@ -57,32 +207,35 @@ func main() {
     for i := range words {
         words[i] = strings.TrimSpace(words[i])
     }
- 
+    return words
+}
+
+//
+// Adds the count of each word in words to freq.
+//
+func countWords(words []string, freq map[string]int) {
     //
-    // Create a map of the counts of all the words. A map is a hash table. The
-    // keys are strings, and the corresponding values are the count of the
-    // number of times the string appears.
+    // freq is a map of the counts of all the words. A map is a hash table.
+    // The keys are strings, and the corresponding values are the count of
+    // the number of times the string appears.
     //
-    // The map is created initially empty. If a string w is not in the map,
-    // then freq[w] returns 0, i.e. the zero-value for the type int. This
-    // quite convenient in this situation. Many other languages deal with
-    // values not in a map by raising an error.
+    // If a string w is not in the map, then freq[w] returns 0, i.e. the
+    // zero-value for the type int. This quite convenient in this situation.
+    // Many other languages deal with values not in a map by raising an
+    // error.
     //
-    freq := map[string]int{}
     for _, w := range words {
         if len(w) > 0 {
             freq[w] += 1
         }
     }
+}
 
-    //
-    // Create a key-value struct for sorting the frequencies.
-    //
-    type kv struct {
-        key string // a word
-        val int    // number of times the word occurs
-    }
-
+//
+// Returns the N most frequent words in freq, or all of them if N is 0 or
+// there are fewer than N.
+//
+func topWords(freq map[string]int, N int) []kv {
     //
     // Copy the frequencies into a slice of key-value pairs.
     //
@@ -116,10 +269,78 @@ func main() {
     })
 
     //
-    // Print the top N most frequently occurring words.
+    // arr[:N] would panic if there are fewer than N words, so use min to
+    // keep N in range.
     //
-    N := 100
-    for i, pair := range arr[:N] {
-        fmt.Printf("%v. %v (%v)\n", i + 1, pair.key, pair.val)
+    if N == 0 {
+        return arr
     }
-} // main
+    return arr[:min(N, len(arr))]
+}
+
+//
+// Options for unicodeWords.
+//
+type tokenizerOptions struct {
+    apostrophes bool // keep apostrophes between letters, e.g. "don't"
+    hyphens     bool // keep hyphens between letters, e.g. "well-known"
+    nfc         bool // normalize to NFC, so "cafe\u0301" is the same as "café"
+}
+
+//
+// Returns the words in content. A word is a run of Unicode letters (in any
+// alphabet), along with any accents and other marks on them. Words are case
+// folded (see foldRune in accents.go), so "Café" and "CAFÉ" are the same
+// word, "café". If opts.nfc is true they're normalized to NFC too, so
+// "cafe\u0301" is also "café".
+//
+// If opts.apostrophes is true, an apostrophe with letters on both sides is
+// part of the word, so "don't" is one word rather than "don" and "t". The
+// curly apostrophe ’ is changed to '. opts.hyphens does the same for hyphens.
+//
+func unicodeWords(content string, opts tokenizerOptions) []string {
+    if opts.nfc {
+        content = nfc(content)
+    }
+    runes := []rune(content)
+    isWordRune := func(r rune) bool {
+        // unicode.M is marks, like accents that aren't part of a precomposed
+        // letter, and the vowel signs in scripts like Hindi
+        return unicode.IsLetter(r) || unicode.Is(unicode.M, r)
+    }
+
+    var words []string
+    var word []rune
+    for i, r := range runes {
+        switch {
+        case isWordRune(r):
+            word = append(word, foldRune(r))
+            continue
+        case r == '\'' || r == '’':
+            if opts.apostrophes && joinsLetters(runes, i, isWordRune) {
+                word = append(word, '\'')
+                continue
+            }
+        case r == '-' || r == '‐': // hyphen-minus and Unicode hyphen
+            if opts.hyphens && joinsLetters(runes, i, isWordRune) {
+                word = append(word, '-')
+                continue
+            }
+        }
+        if len(word) > 0 {
+            words = append(words, string(word))
+            word = nil
+        }
+    }
+    if len(word) > 0 {
+        words = append(words, string(word))
+    }
+    return words
+}
+
+//
+// Returns true if runes[i] has a letter on both sides.
+//
+func joinsLetters(runes []rune, i int, isWordRune func(rune) bool) bool {
+    return i > 0 && i+1 < len(runes) && isWordRune(runes[i-1]) && isWordRune(runes[i+1])
+}
@@ -0,0 +1,138 @@
+// wordcount_test.go
+
+//
+// Tests for wordcount.go. Run them like this:
+//
+//    $ go test wordcount.go accents.go wordcount_test.go
+//
+
+package main
+
+import (
+    "fmt"
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+)
+
+func TestTopWords(t *testing.T) {
+    freq := map[string]int{"the": 3, "cat": 1, "sat": 1, "mat": 2, "a": 1}
+    tests := []struct {
+        name     string
+        freq     map[string]int
+        N        int
+        expected string
+    }{
+        {"top 2", freq, 2, "[{the 3} {mat 2}]"},
+        {"fewer than N", freq, 10, "[{the 3} {mat 2} {a 1} {cat 1} {sat 1}]"},
+        {"N=0 is all", freq, 0, "[{the 3} {mat 2} {a 1} {cat 1} {sat 1}]"},
+        {"ties alphabetical", map[string]int{"b": 1, "c": 1, "a": 1}, 2, "[{a 1} {b 1}]"},
+        {"no words", map[string]int{}, 5, "[]"},
+    }
+    for _, test := range tests {
+        if got := fmt.Sprint(topWords(test.freq, test.N)); got != test.expected {
+            t.Errorf("%v: topWords(%v, %v) = %v, expected %v",
+                     test.name, test.freq, test.N, got, test.expected)
+        }
+    }
+}
+
+func TestUnicodeWords(t *testing.T) {
+    all := tokenizerOptions{apostrophes: true, hyphens: true, nfc: true}
+    none := tokenizerOptions{}
+    tests := []struct {
+        content  string
+        opts     tokenizerOptions
+        expected []string
+    }{
+        // precomposed é, and e with a combining acute accent
+        {"café CAFÉ cafe\u0301", all, []string{"café", "café", "café"}},
+        {"café CAFÉ cafe\u0301", none, []string{"café", "café", "cafe\u0301"}},
+        // straight and curly apostrophes
+        {"don't don’t", all, []string{"don't", "don't"}},
+        {"don't don’t", none, []string{"don", "t", "don", "t"}},
+        // hyphen-minus and Unicode hyphen
+        {"well-known well‐known", all, []string{"well-known", "well-known"}},
+        {"well-known", none, []string{"well", "known"}},
+        {"--dash- -", all, []string{"dash"}},
+        // Greek final sigma ς folds to σ, like Σ does
+        {"ΣΟΦΟΣ σοφος σοφοσ", none, []string{"σοφοσ", "σοφοσ", "σοφοσ"}},
+        // an apostrophe at the start or end of a word isn't part of it
+        {"'tis the dogs' bones", all, []string{"tis", "the", "dogs", "bones"}},
+        {"’Twas ''", all, []string{"twas"}},
+        {"naïve 東京 123", all, []string{"naïve", "東京"}},
+        {"", all, nil},
+    }
+    for _, test := range tests {
+        got := unicodeWords(test.content, test.opts)
+        if fmt.Sprintf("%q", got) != fmt.Sprintf("%q", test.expected) {
+            t.Errorf("unicodeWords(%q, %+v) = %q, expected %q",
+                     test.content, test.opts, got, test.expected)
+        }
+    }
+}
+
+func TestReadFile(t *testing.T) {
+    fname := filepath.Join(t.TempDir(), "words.txt")
+    if err := os.WriteFile(fname, []byte("from a file"), 0644); err != nil {
+        t.Fatal(err)
+    }
+    stdin := strings.NewReader("from stdin")
+
+    if got, err := readFile(fname, stdin); got != "from a file" || err != nil {
+        t.Errorf("readFile(%v) = %q, %v, expected \"from a file\"", fname, got, err)
+    }
+    if got, err := readFile("-", stdin); got != "from stdin" || err != nil {
+        t.Errorf("readFile(-) = %q, %v, expected \"from stdin\"", got, err)
+    }
+    missing := filepath.Join(t.TempDir(), "nosuch.txt")
+    if got, err := readFile(missing, stdin); err == nil {
+        t.Errorf("readFile(%v) = %q, expected an error", missing, got)
+    }
+}
+
+//
+// Runs wordcount with each set of arguments, and checks the exit status and
+// the start of what it prints. Errors go to stderr, and nothing else should.
+//
+func TestRun(t *testing.T) {
+    fname := filepath.Join(t.TempDir(), "words.txt")
+    if err := os.WriteFile(fname, []byte("the cat and the hat"), 0644); err != nil {
+        t.Fatal(err)
+    }
+    missing := filepath.Join(t.TempDir(), "nosuch.txt")
+
+    tests := []struct {
+        args   []string
+        stdin  string
+        status int
+        stdout string // what stdout should be
+        stderr string // what stderr should start with
+    }{
+        {[]string{"-n", "2", fname}, "", 0, "1. the (2)\n2. and (1)\n", ""},
+        {[]string{"-n", "1"}, "dog dog cat", 0, "1. dog (2)\n", ""},
+        {[]string{"-"}, "123 ...", 0, "no words found\n", ""},
+        {[]string{missing}, "", 1, "", "wordcount: open " + missing},
+        {[]string{fname, missing}, "", 1, "", "wordcount: open " + missing},
+        {[]string{"-n", "-1", fname}, "", 2, "", "wordcount: -n must be 0 or more"},
+        {[]string{"-n", "many", fname}, "", 2, "", "invalid value \"many\" for flag -n"},
+        {[]string{"-tokenizer", "words", fname}, "", 2, "", "wordcount: unknown tokenizer \"words\""},
+        {[]string{"-hyphens", fname}, "", 2, "", "wordcount: -apostrophes, -hyphens and -nfc need"},
+        {[]string{"-nfc=false", fname}, "", 2, "", "wordcount: -apostrophes, -hyphens and -nfc need"},
+        {[]string{"-tokenizer=unicode", "-nfc=false"}, "Café cafe\u0301", 0,
+         "1. cafe\u0301 (1)\n2. café (1)\n", ""},
+        {[]string{"-nosuchflag"}, "", 2, "", "flag provided but not defined: -nosuchflag"},
+    }
+    for _, test := range tests {
+        var stdout, stderr strings.Builder
+        status := run(test.args, strings.NewReader(test.stdin), &stdout, &stderr)
+        if status != test.status || stdout.String() != test.stdout ||
+           !strings.HasPrefix(stderr.String(), test.stderr) ||
+           (test.stderr == "" && stderr.Len() > 0) {
+            t.Errorf("run(%q) = %v, printed %q and %q, expected %v, %q and %q...",
+                     test.args, status, stdout.String(), stderr.String(),
+                     test.status, test.stdout, test.stderr)
+        }
+    }
+}